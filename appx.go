@@ -0,0 +1,78 @@
+package lnk
+
+import (
+	"bytes"
+	"strings"
+)
+
+// appsFolderCLSID is the CLSID of the Applications shell folder, which
+// contains packaged (UWP/AppX) applications.
+var appsFolderCLSID = guid("4234D49B-0245-4DF3-B780-3893943456E1")
+
+// IsAppExecutionAlias reports whether the shortcut launches a packaged
+// (UWP/AppX) application rather than a file. Such shortcuts have no
+// filesystem target, so LocalBasePath is empty.
+//
+// A shortcut is classified as packaged if its IDList is rooted at the
+// Applications folder ({4234D49B-0245-4DF3-B780-3893943456E1}), or if it has a
+// package family name (see PackageFamilyName).
+func (lnk *LNK) IsAppExecutionAlias() bool {
+	if lnk.inAppsFolder() {
+		return true
+	}
+	_, ok := lnk.PackageFamilyName()
+	return ok
+}
+
+// PackageFamilyName returns the package family name of the packaged
+// application the shortcut launches. It is taken from the
+// System.AppUserModel.ID property, which for packaged applications has the
+// form "PackageFamilyName!ApplicationID". The property is looked up in the
+// PropertyStoreDataBlock, then in the property storages embedded in the
+// ItemIDs beneath the Applications folder.
+func (lnk *LNK) PackageFamilyName() (string, bool) {
+	if id, ok := lnk.AppUserModelID(); ok {
+		return packageFamilyName(id)
+	}
+	if !lnk.inAppsFolder() {
+		return "", false
+	}
+
+	items, _ := lnk.ItemIDsWithOptions(IDListOptions{Lenient: true})
+	for _, item := range items[1:] {
+		start := bytes.Index(item, []byte("1SPS"))
+		if start < 4 {
+			continue
+		}
+		props, _ := readPropertyStore(item[start-4:])
+		for _, prop := range props {
+			id, ok := prop.Value.(string)
+			if ok && prop.PropertyKey == appUserModelIDKey {
+				return packageFamilyName(id)
+			}
+		}
+	}
+	return "", false
+}
+
+// inAppsFolder reports whether the first ItemID is a root folder shell item
+// for the Applications folder.
+func (lnk *LNK) inAppsFolder() bool {
+	items, _ := lnk.ItemIDsWithOptions(IDListOptions{Lenient: true})
+	if len(items) == 0 || len(items[0]) < 18 || items[0][0] != 0x1f {
+		return false
+	}
+	var clsid GUID
+	copy(clsid[:], items[0][2:])
+	return clsid == appsFolderCLSID
+}
+
+// packageFamilyName extracts the package family name from an AppUserModelID.
+// Package family names have the form "Name_PublisherID".
+func packageFamilyName(appUserModelID string) (string, bool) {
+	i := strings.IndexByte(appUserModelID, '!')
+	if i <= 0 || !strings.Contains(appUserModelID[:i], "_") {
+		return "", false
+	}
+	return appUserModelID[:i], true
+}
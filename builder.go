@@ -0,0 +1,241 @@
+package lnk
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath is returned when a Builder is given a target path it
+	// cannot encode.
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrNotANSI is returned when a Builder that writes ANSI strings is given
+	// a string that is not ASCII.
+	ErrNotANSI = errors.New("string is not ASCII")
+)
+
+// DriveFixed is the value of LNK.DriveType for fixed (hard) drives.
+const DriveFixed = 3
+
+// NetworkProviderLanman is the value of LNK.NetworkProviderType for Windows
+// (SMB) shares, WNNC_NET_LANMAN.
+const NetworkProviderLanman = 0x00020000
+
+// Builder creates shortcuts, computing the flags and structures that have to
+// agree with each other.
+type Builder struct {
+	lnk LNK
+
+	serialNumber uint32
+	label        string
+}
+
+// NewBuilder returns a Builder for a Unicode shortcut that opens its target
+// normally.
+func NewBuilder() *Builder {
+	b := new(Builder)
+	b.lnk.CLSID = validCLSID
+	b.lnk.IsUnicode = true
+	b.lnk.ShowCommand = ShowNormal
+	return b
+}
+
+// SetUnicode sets whether StringData is written as UTF-16 rather than ANSI.
+// Shortcuts are Unicode by default, but some legacy tools can only read ANSI
+// ones, in which case every string must be ASCII, as the code page of the
+// reader is unknown.
+func (b *Builder) SetUnicode(unicode bool) {
+	b.lnk.IsUnicode = unicode
+}
+
+// SetVolume sets the drive serial number and volume label recorded in the
+// VolumeID of local targets. It may be called before or after SetTargetPath.
+func (b *Builder) SetVolume(serialNumber uint32, label string) {
+	b.serialNumber = serialNumber
+	b.label = label
+}
+
+// SetTargetPath targets a local path, such as
+// `C:\Program Files\App\app.exe`. It synthesizes a LinkInfo holding a VolumeID
+// for a fixed drive and the path as LocalBasePath. It replaces any network
+// target set by SetNetworkTarget. A trailing backslash marks the target as a
+// directory.
+//
+// The Builder only sets the ANSI LinkInfo strings, so the path must be ASCII.
+func (b *Builder) SetTargetPath(path string) error {
+	if len(path) < 3 || path[1] != ':' || path[2] != '\\' || !isLetter(path[0]) {
+		return ErrInvalidPath
+	}
+	if strings.IndexByte(path, 0) != -1 || !isASCII(path) {
+		return ErrInvalidPath
+	}
+
+	directory := strings.HasSuffix(path, `\`)
+	if directory && len(path) > 3 {
+		path = strings.TrimRight(path, `\`)
+	}
+
+	b.clearLinkInfo()
+	b.lnk.VolumeIDAndLocalBasePath = true
+	b.lnk.DriveType = DriveFixed
+	b.lnk.LocalBasePath = path
+	b.lnk.LocalBasePathANSI = path
+	b.lnk.Directory = directory
+	b.lnk.Archive = !directory
+	return nil
+}
+
+// SetNetworkTarget targets a file on a network share by its UNC path, such as
+// `\\server\share\tool.exe`. It synthesizes a LinkInfo holding a
+// CommonNetworkRelativeLink whose NetName is the share, `\\server\share`, and
+// the rest of the path as CommonPathSuffix, so that UNCPath returns the
+// original path. It replaces any local target set by SetTargetPath. A trailing
+// backslash marks the target as a directory.
+//
+// The Builder only sets the ANSI LinkInfo strings, so the path must be ASCII.
+func (b *Builder) SetNetworkTarget(uncPath string) error {
+	if !strings.HasPrefix(uncPath, `\\`) || strings.IndexByte(uncPath, 0) != -1 || !isASCII(uncPath) {
+		return ErrInvalidPath
+	}
+	parts := strings.SplitN(uncPath[2:], `\`, 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ErrInvalidPath
+	}
+
+	var suffix string
+	if len(parts) == 3 {
+		suffix = parts[2]
+	}
+	directory := suffix == "" || strings.HasSuffix(suffix, `\`)
+	suffix = strings.TrimRight(suffix, `\`)
+
+	b.clearLinkInfo()
+	b.lnk.CommonNetworkRelativeLinkAndPathSuffix = true
+	b.lnk.ValidNetType = true
+	b.lnk.NetworkProviderType = NetworkProviderLanman
+	b.lnk.NetName = `\\` + parts[0] + `\` + parts[1]
+	b.lnk.NetNameANSI = b.lnk.NetName
+	b.lnk.CommonPathSuffix = suffix
+	b.lnk.CommonPathSuffixANSI = suffix
+	b.lnk.Directory = directory
+	b.lnk.Archive = !directory
+	return nil
+}
+
+// clearLinkInfo replaces the target set by SetTargetPath or SetNetworkTarget
+// with an empty LinkInfo.
+func (b *Builder) clearLinkInfo() {
+	lnk := &b.lnk
+	lnk.HasLinkInfo = true
+	lnk.ForceNoLinkInfo = false
+	lnk.VolumeIDAndLocalBasePath = false
+	lnk.CommonNetworkRelativeLinkAndPathSuffix = false
+	lnk.DriveType = 0
+	lnk.VolumeLabel, lnk.VolumeLabelANSI, lnk.VolumeLabelUnicode = "", "", ""
+	lnk.LocalBasePath, lnk.LocalBasePathANSI, lnk.LocalBasePathUnicode = "", "", ""
+	lnk.ValidDevice = false
+	lnk.ValidNetType = false
+	lnk.NetName, lnk.NetNameANSI, lnk.NetNameUnicode = "", "", ""
+	lnk.DeviceName, lnk.DeviceNameANSI, lnk.DeviceNameUnicode = "", "", ""
+	lnk.NetworkProviderType = 0
+	lnk.CommonPathSuffix, lnk.CommonPathSuffixANSI, lnk.CommonPathSuffixUnicode = "", "", ""
+}
+
+// SetName sets the description of the shortcut.
+func (b *Builder) SetName(name string) {
+	b.lnk.HasName = true
+	b.lnk.Name = name
+}
+
+// SetRelativePath sets the path of the target relative to the shortcut.
+func (b *Builder) SetRelativePath(path string) {
+	b.lnk.HasRelativePath = true
+	b.lnk.RelativePath = path
+}
+
+// SetWorkingDir sets the working directory the target is started in.
+func (b *Builder) SetWorkingDir(dir string) {
+	b.lnk.HasWorkingDir = true
+	b.lnk.WorkingDir = dir
+}
+
+// SetArguments sets the command-line arguments passed to the target.
+func (b *Builder) SetArguments(args string) {
+	b.lnk.HasArguments = true
+	b.lnk.Arguments = args
+}
+
+// SetIconLocation sets the file the icon is taken from and its index.
+func (b *Builder) SetIconLocation(path string, index int32) {
+	b.lnk.HasIconLocation = true
+	b.lnk.IconLocation = path
+	b.lnk.IconIndex = index
+}
+
+// AddEnvironmentVariable adds an EnvironmentVariableDataBlock, which targets a
+// path containing environment variables, such as `%ProgramFiles%\App\app.exe`.
+// Paths are truncated to 259 characters.
+func (b *Builder) AddEnvironmentVariable(ansi, unicode string) {
+	b.lnk.HasExpString = true
+	b.lnk.EnvironmentVariable = &EnvironmentVariableData{ansi, unicode}
+}
+
+// AddIconEnvironment adds an IconEnvironmentDataBlock, which locates the icon
+// by a path containing environment variables. Paths are truncated to 259
+// characters.
+func (b *Builder) AddIconEnvironment(ansi, unicode string) {
+	b.lnk.HasExpIcon = true
+	b.lnk.IconEnvironment = &EnvironmentVariableData{ansi, unicode}
+}
+
+// SetAppUserModelID sets the System.AppUserModel.ID property, which the
+// taskbar uses to group the windows of the target with the shortcut.
+func (b *Builder) SetAppUserModelID(id string) {
+	prop := Property{PropertyKey: appUserModelIDKey, Type: vtLPWSTR, Value: id}
+	for i := range b.lnk.PropertyStore {
+		if b.lnk.PropertyStore[i].Name == "" && b.lnk.PropertyStore[i].PropertyKey == appUserModelIDKey {
+			b.lnk.PropertyStore[i] = prop
+			return
+		}
+	}
+	b.lnk.PropertyStore = append(b.lnk.PropertyStore, prop)
+}
+
+// Build returns the shortcut.
+func (b *Builder) Build() *LNK {
+	lnk := b.lnk
+	if lnk.VolumeIDAndLocalBasePath {
+		lnk.DriveSerialNumber = b.serialNumber
+		lnk.VolumeLabel = b.label
+		lnk.VolumeLabelANSI = b.label
+	}
+	return &lnk
+}
+
+// WriteTo writes the shortcut in the .lnk file format. If the shortcut is not
+// Unicode and one of its strings is not ASCII, ErrNotANSI is returned.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	if !b.lnk.IsUnicode {
+		for _, str := range []string{b.lnk.Name, b.lnk.RelativePath, b.lnk.WorkingDir, b.lnk.Arguments, b.lnk.IconLocation} {
+			if !isASCII(str) {
+				return 0, ErrNotANSI
+			}
+		}
+	}
+	return b.Build().WriteTo(w)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isASCII(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,239 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// roundTrip writes the shortcut built by b and parses it back.
+func roundTrip(t *testing.T, b *Builder) *LNK {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lnk, err := ParseWithOptions(&buf, ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lnk
+}
+
+func TestBuilderSetTargetPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		want      string
+		directory bool
+	}{
+		{`C:\Program Files\App\app.exe`, `C:\Program Files\App\app.exe`, false},
+		{`D:\Data\`, `D:\Data`, true},
+		{`C:\`, `C:\`, true},
+	}
+	for _, test := range tests {
+		b := NewBuilder()
+		b.SetVolume(0xdeadbeef, "OS")
+		if err := b.SetTargetPath(test.path); err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		lnk := roundTrip(t, b)
+
+		if target, err := lnk.ResolveTarget(); err != nil || target != test.want {
+			t.Errorf("%s: ResolveTarget() = %q, %v, want %q", test.path, target, err, test.want)
+		}
+		if lnk.VolumeLabel != "OS" || lnk.DriveSerialNumber != 0xdeadbeef || lnk.DriveType != DriveFixed {
+			t.Errorf("%s: VolumeLabel = %q, DriveSerialNumber = 0x%x, DriveType = %d", test.path, lnk.VolumeLabel, lnk.DriveSerialNumber, lnk.DriveType)
+		}
+		if lnk.Directory != test.directory {
+			t.Errorf("%s: Directory = %v, want %v", test.path, lnk.Directory, test.directory)
+		}
+	}
+}
+
+func TestBuilderSetTargetPathInvalid(t *testing.T) {
+	for _, path := range []string{"", `app.exe`, `C:app.exe`, `\\server\share`, "C:\\caf\u00e9.exe", "C:\\a\x00b"} {
+		if err := NewBuilder().SetTargetPath(path); err != ErrInvalidPath {
+			t.Errorf("SetTargetPath(%q) error = %v, want %v", path, err, ErrInvalidPath)
+		}
+	}
+}
+
+func TestBuilderStrings(t *testing.T) {
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\Tools\app.exe`); err != nil {
+		t.Fatal(err)
+	}
+	b.SetName("App")
+	b.SetRelativePath(`..\Tools\app.exe`)
+	b.SetWorkingDir(`C:\Tools`)
+	b.SetArguments(`--verbose "C:\My Files"`)
+	b.SetIconLocation(`C:\Tools\app.ico`, 2)
+	lnk := roundTrip(t, b)
+
+	if lnk.Name != "App" || lnk.RelativePath != `..\Tools\app.exe` || lnk.WorkingDir != `C:\Tools` ||
+		lnk.Arguments != `--verbose "C:\My Files"` || lnk.IconLocation != `C:\Tools\app.ico` || lnk.IconIndex != 2 {
+		t.Errorf("StringData = %q, %q, %q, %q, %q, %d", lnk.Name, lnk.RelativePath, lnk.WorkingDir, lnk.Arguments, lnk.IconLocation, lnk.IconIndex)
+	}
+}
+
+func TestBuilderExtraData(t *testing.T) {
+	b := NewBuilder()
+	b.AddEnvironmentVariable(`%windir%\a.exe`, `%windir%\a.exe`)
+	b.AddIconEnvironment(`%windir%\b.ico`, `%windir%\b.ico`)
+	b.SetAppUserModelID("Old.App")
+	b.SetAppUserModelID("Contoso.App")
+	data, err := b.Build().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(data, []byte{0, 0, 0, 0}) {
+		t.Error("the TerminalBlock is not last")
+	}
+
+	lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.EnvironmentVariable == nil || lnk.EnvironmentVariable.Target() != `%windir%\a.exe` || !lnk.HasExpString {
+		t.Errorf("EnvironmentVariable = %+v, HasExpString = %v", lnk.EnvironmentVariable, lnk.HasExpString)
+	}
+	if lnk.IconEnvironment == nil || lnk.IconEnvironment.TargetUnicode != `%windir%\b.ico` || !lnk.HasExpIcon {
+		t.Errorf("IconEnvironment = %+v, HasExpIcon = %v", lnk.IconEnvironment, lnk.HasExpIcon)
+	}
+	if id, ok := lnk.AppUserModelID(); id != "Contoso.App" || !ok || len(lnk.PropertyStore) != 1 {
+		t.Errorf("AppUserModelID() = %q, %v with %d properties", id, ok, len(lnk.PropertyStore))
+	}
+
+	again, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("writing the parsed shortcut changed it")
+	}
+}
+
+func TestBuilderSetUnicode(t *testing.T) {
+	b := NewBuilder()
+	b.SetUnicode(false)
+	b.SetArguments("-x y")
+	lnk := roundTrip(t, b)
+	if lnk.IsUnicode || lnk.Arguments != "-x y" {
+		t.Errorf("IsUnicode = %v, Arguments = %q, want ANSI %q", lnk.IsUnicode, lnk.Arguments, "-x y")
+	}
+
+	b.SetName("café")
+	if _, err := b.WriteTo(io.Discard); !errors.Is(err, ErrNotANSI) {
+		t.Errorf("WriteTo() error = %v, want %v", err, ErrNotANSI)
+	}
+
+	b.SetUnicode(true)
+	lnk = roundTrip(t, b)
+	if !lnk.IsUnicode || lnk.Name != "café" || lnk.Arguments != "-x y" {
+		t.Errorf("IsUnicode = %v, Name = %q, Arguments = %q", lnk.IsUnicode, lnk.Name, lnk.Arguments)
+	}
+}
+
+func TestBuilderSetNetworkTarget(t *testing.T) {
+	tests := []struct {
+		path      string
+		netName   string
+		suffix    string
+		directory bool
+	}{
+		{`\\server\share\tool.exe`, `\\server\share`, `tool.exe`, false},
+		{`\\server\share\a\b\tool.exe`, `\\server\share`, `a\b\tool.exe`, false},
+		{`\\server\share\docs\`, `\\server\share`, `docs`, true},
+		{`\\server\share`, `\\server\share`, ``, true},
+	}
+	for _, test := range tests {
+		b := NewBuilder()
+		if err := b.SetTargetPath(`C:\local.exe`); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetNetworkTarget(test.path); err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		lnk := roundTrip(t, b)
+
+		want := strings.TrimSuffix(test.path, `\`)
+		if got := lnk.UNCPath(); got != want {
+			t.Errorf("%s: UNCPath() = %q, want %q", test.path, got, want)
+		}
+		if got, err := lnk.ResolveTarget(); err != nil || got != want {
+			t.Errorf("%s: ResolveTarget() = %q, %v, want %q", test.path, got, err, want)
+		}
+		if lnk.NetName != test.netName || lnk.CommonPathSuffix != test.suffix || lnk.NetworkProviderType != NetworkProviderLanman || !lnk.ValidNetType {
+			t.Errorf("%s: NetName = %q, CommonPathSuffix = %q, NetworkProviderType = %#x", test.path, lnk.NetName, lnk.CommonPathSuffix, lnk.NetworkProviderType)
+		}
+		// the local target is replaced
+		if lnk.VolumeIDAndLocalBasePath || lnk.LocalBasePath != "" || lnk.BothLocalAndNetwork() {
+			t.Errorf("%s: LocalBasePath = %q", test.path, lnk.LocalBasePath)
+		}
+		if lnk.Directory != test.directory {
+			t.Errorf("%s: Directory = %v, want %v", test.path, lnk.Directory, test.directory)
+		}
+	}
+}
+
+func TestBuilderTargetOrder(t *testing.T) {
+	// whichever target is set last replaces the other, and the volume applies
+	// to the local target whenever it is set
+	local := func(b *Builder) error {
+		return b.SetTargetPath(`C:\app\app.exe`)
+	}
+	network := func(b *Builder) error {
+		return b.SetNetworkTarget(`\\server\share\tool.exe`)
+	}
+	volume := func(b *Builder) error {
+		b.SetVolume(0xdeadbeef, "OS")
+		return nil
+	}
+	tests := []struct {
+		name    string
+		setters []func(*Builder) error
+		want    string
+		label   string
+	}{
+		{"network then local", []func(*Builder) error{network, local}, `C:\app\app.exe`, ""},
+		{"local then network", []func(*Builder) error{local, network}, `\\server\share\tool.exe`, ""},
+		{"volume then local", []func(*Builder) error{volume, local}, `C:\app\app.exe`, "OS"},
+		{"local then volume", []func(*Builder) error{local, volume}, `C:\app\app.exe`, "OS"},
+		{"volume, local then network", []func(*Builder) error{volume, local, network}, `\\server\share\tool.exe`, ""},
+	}
+	for _, test := range tests {
+		b := NewBuilder()
+		for _, set := range test.setters {
+			if err := set(b); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		lnk := roundTrip(t, b)
+
+		if got, err := lnk.ResolveTarget(); err != nil || got != test.want {
+			t.Errorf("%s: ResolveTarget() = %q, %v, want %q", test.name, got, err, test.want)
+		}
+		if lnk.BothLocalAndNetwork() {
+			t.Errorf("%s: both a local and a network target", test.name)
+		}
+		if lnk.VolumeLabel != test.label {
+			t.Errorf("%s: VolumeLabel = %q, want %q", test.name, lnk.VolumeLabel, test.label)
+		}
+		if test.label != "" && lnk.DriveSerialNumber != 0xdeadbeef {
+			t.Errorf("%s: DriveSerialNumber = 0x%x, want 0xdeadbeef", test.name, lnk.DriveSerialNumber)
+		}
+		if lnk.IsNetworkTarget() != strings.HasPrefix(test.want, `\\`) || (lnk.IsNetworkTarget() && lnk.NetworkProviderType != NetworkProviderLanman) {
+			t.Errorf("%s: IsNetworkTarget() = %v, NetworkProviderType = %#x", test.name, lnk.IsNetworkTarget(), lnk.NetworkProviderType)
+		}
+	}
+}
+
+func TestBuilderSetNetworkTargetInvalid(t *testing.T) {
+	for _, path := range []string{"", `C:\x`, `\\server`, `\\\share`, `\\server\`, "\\\\server\\caf\u00e9"} {
+		if err := NewBuilder().SetNetworkTarget(path); err != ErrInvalidPath {
+			t.Errorf("SetNetworkTarget(%q) error = %v, want %v", path, err, ErrInvalidPath)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrNotACompoundFile is returned when a compound file does not start
+	// with the compound file signature.
+	ErrNotACompoundFile = errors.New("not a compound file")
+
+	// ErrStreamNotFound is returned when a compound file does not contain the
+	// requested stream.
+	ErrStreamNotFound = errors.New("stream not found")
+
+	// ErrInvalidSectorChain is returned when a compound file sector chain
+	// loops or points outside of its allocation table.
+	ErrInvalidSectorChain = errors.New("invalid sector chain")
+)
+
+// D0CF11E0-A1B11AE1
+var cfbSignature = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+// special sector numbers (MS-CFB 2.1)
+const (
+	cfbMaxRegSect  = 0xfffffffa
+	cfbEndOfChain  = 0xfffffffe
+	cfbNoStream    = 0xffffffff
+	cfbStreamEntry = 2
+)
+
+// cfbEntry is a compound file directory entry (MS-CFB 2.6).
+type cfbEntry struct {
+	name        string
+	objectType  byte
+	left, right uint32
+	child       uint32
+	start       uint32
+	size        uint64
+}
+
+// compoundFile is the minimal state needed to read streams out of a compound
+// file.
+type compoundFile struct {
+	r          io.ReaderAt
+	sectorSize int64
+	miniSize   int64
+	miniCutoff uint64
+	fat        []uint32
+	miniFAT    []uint32
+	entries    []cfbEntry
+}
+
+// ParseCFBStream parses a shortcut that is stored as a stream inside a
+// compound file (MS-CFB), such as those embedded in older Office documents.
+//
+// streamName is the name of the stream; streams inside storages are named by
+// their path, separated by slashes (e.g. "Storage/Stream"). Names are
+// compared case-insensitively, as in the compound file format itself.
+//
+// A compound file starts with a 512-byte header that locates the sector
+// allocation table (FAT) and the directory. The directory is a tree of named
+// storages and streams; a stream is a chain of sectors in the FAT, or, when
+// it is smaller than the mini stream cutoff (usually 4096 bytes), a chain of
+// 64-byte sectors in the mini FAT that live inside the root entry's stream.
+// Callers that have already extracted the stream should pass it to Parse.
+func ParseCFBStream(r io.ReaderAt, streamName string) (*LNK, error) {
+	cf, err := openCompoundFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := cf.stream(streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(bytes.NewReader(stream))
+}
+
+func openCompoundFile(r io.ReaderAt) (*compoundFile, error) {
+	header := make([]byte, 512)
+	_, err := r.ReadAt(header, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:8], cfbSignature) {
+		return nil, ErrNotACompoundFile
+	}
+
+	cf := &compoundFile{r: r}
+	sectorShift := endianness.Uint16(header[0x1e:])
+	miniShift := endianness.Uint16(header[0x20:])
+	if (sectorShift != 9 && sectorShift != 12) || miniShift != 6 {
+		return nil, ErrInvalidSize
+	}
+	cf.sectorSize = 1 << sectorShift
+	cf.miniSize = 1 << miniShift
+	cf.miniCutoff = uint64(endianness.Uint32(header[0x38:]))
+
+	// DIFAT: the first 109 FAT sector locations are in the header, the rest
+	// are in a chain of DIFAT sectors whose last entry is the next sector
+	numFATSectors := endianness.Uint32(header[0x2c:])
+	var difat []uint32
+	for i := 0; i < 109; i++ {
+		difat = append(difat, endianness.Uint32(header[0x4c+4*i:]))
+	}
+	next := endianness.Uint32(header[0x44:])
+	numDIFATSectors := endianness.Uint32(header[0x48:])
+	// every sector of the chain must be a distinct sector of the file, so a
+	// chain that revisits one loops
+	visited := make(map[uint32]bool)
+	for i := uint32(0); i < numDIFATSectors && next <= cfbMaxRegSect; i++ {
+		if visited[next] {
+			return nil, ErrInvalidSectorChain
+		}
+		visited[next] = true
+		sector, err := cf.sector(next)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < len(sector)-4; j += 4 {
+			difat = append(difat, endianness.Uint32(sector[j:]))
+		}
+		next = endianness.Uint32(sector[len(sector)-4:])
+	}
+
+	for i := 0; i < len(difat) && uint32(i) < numFATSectors; i++ {
+		if difat[i] > cfbMaxRegSect {
+			continue
+		}
+		sector, err := cf.sector(difat[i])
+		if err != nil {
+			return nil, err
+		}
+		cf.fat = append(cf.fat, uint32s(sector)...)
+	}
+
+	dir, err := cf.chain(endianness.Uint32(header[0x30:]), cf.fat, cf.sector)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+128 <= len(dir); i += 128 {
+		entry := dir[i : i+128]
+		nameLength := int(endianness.Uint16(entry[0x40:]))
+		if nameLength > 64 {
+			nameLength = 64
+		}
+		size := endianness.Uint64(entry[0x78:])
+		if sectorShift == 9 {
+			// the high 32 bits may be garbage in version 3 files
+			size &= 0xffffffff
+		}
+		cf.entries = append(cf.entries, cfbEntry{
+			name:       decodeUTF16(entry[:nameLength]),
+			objectType: entry[0x42],
+			left:       endianness.Uint32(entry[0x44:]),
+			right:      endianness.Uint32(entry[0x48:]),
+			child:      endianness.Uint32(entry[0x4c:]),
+			start:      endianness.Uint32(entry[0x74:]),
+			size:       size,
+		})
+	}
+	if len(cf.entries) == 0 {
+		return nil, ErrInvalidSize
+	}
+
+	miniFAT, err := cf.chain(endianness.Uint32(header[0x3c:]), cf.fat, cf.sector)
+	if err != nil {
+		return nil, err
+	}
+	cf.miniFAT = uint32s(miniFAT)
+
+	return cf, nil
+}
+
+// stream returns the contents of the stream at path.
+func (cf *compoundFile) stream(path string) ([]byte, error) {
+	entry := &cf.entries[0]
+	for _, name := range strings.Split(path, "/") {
+		entry = cf.find(entry.child, name)
+		if entry == nil {
+			return nil, ErrStreamNotFound
+		}
+	}
+	if entry.objectType != cfbStreamEntry {
+		return nil, ErrStreamNotFound
+	}
+
+	var data []byte
+	var err error
+	if entry.size < cf.miniCutoff {
+		root := cf.entries[0]
+		var miniStream []byte
+		miniStream, err = cf.chain(root.start, cf.fat, cf.sector)
+		if err != nil {
+			return nil, err
+		}
+		data, err = cf.chain(entry.start, cf.miniFAT, func(n uint32) ([]byte, error) {
+			offset := int64(n) * cf.miniSize
+			if offset+cf.miniSize > int64(len(miniStream)) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return miniStream[offset : offset+cf.miniSize], nil
+		})
+	} else {
+		data, err = cf.chain(entry.start, cf.fat, cf.sector)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(data)) < entry.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data[:entry.size], nil
+}
+
+// find searches the red-black tree of siblings rooted at id for an entry
+// named name.
+func (cf *compoundFile) find(id uint32, name string) *cfbEntry {
+	// the tree cannot be deeper than the number of entries, so this also
+	// stops on cycles
+	stack := []uint32{id}
+	for visited := 0; len(stack) > 0 && visited <= len(cf.entries); visited++ {
+		id, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		if id == cfbNoStream || int(id) >= len(cf.entries) {
+			continue
+		}
+
+		entry := &cf.entries[id]
+		if strings.EqualFold(entry.name, name) {
+			return entry
+		}
+		stack = append(stack, entry.left, entry.right)
+	}
+	return nil
+}
+
+// sector reads a regular sector, which are numbered from the end of the
+// header.
+func (cf *compoundFile) sector(n uint32) ([]byte, error) {
+	sector := make([]byte, cf.sectorSize)
+	_, err := cf.r.ReadAt(sector, (int64(n)+1)*cf.sectorSize)
+	if err != nil {
+		return nil, err
+	}
+	return sector, nil
+}
+
+// chain concatenates the sectors of a chain in the allocation table.
+func (cf *compoundFile) chain(start uint32, table []uint32, read func(uint32) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	for n, length := start, 0; n != cfbEndOfChain; n, length = table[n], length+1 {
+		if n > cfbMaxRegSect {
+			return data, nil
+		}
+		if int(n) >= len(table) || length > len(table) {
+			return nil, ErrInvalidSectorChain
+		}
+
+		sector, err := read(n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, sector...)
+	}
+	return data, nil
+}
+
+func uint32s(data []byte) []uint32 {
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = endianness.Uint32(data[4*i:])
+	}
+	return values
+}
@@ -0,0 +1,44 @@
+package lnk
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestParseCFBStream(t *testing.T) {
+	for _, name := range []string{"small.cfb", "big.cfb"} {
+		f, err := os.Open("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		lnk, err := ParseCFBStream(f, "link")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if lnk.LocalBasePath != `C:\test\a.txt` {
+			t.Errorf("%s: LocalBasePath = %q", name, lnk.LocalBasePath)
+		}
+		if _, err := ParseCFBStream(f, "nope"); err != ErrStreamNotFound {
+			t.Errorf("%s: error = %v, want %v", name, err, ErrStreamNotFound)
+		}
+	}
+}
+
+func TestParseCFBStreamDIFATLoop(t *testing.T) {
+	data, err := os.ReadFile("testdata/small.cfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a DIFAT chain of 0xffffffff sectors starting at, and pointing back to,
+	// sector 0
+	endianness.PutUint32(data[0x44:], 0)
+	endianness.PutUint32(data[0x48:], 0xffffffff)
+	endianness.PutUint32(data[512+512-4:], 0)
+
+	if _, err := ParseCFBStream(bytes.NewReader(data), "link"); err != ErrInvalidSectorChain {
+		t.Errorf("error = %v, want %v", err, ErrInvalidSectorChain)
+	}
+}
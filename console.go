@@ -0,0 +1,187 @@
+package lnk
+
+import "image/color"
+
+// ConsoleData is the ConsoleDataBlock, which specifies the display settings
+// of a console window (MS-SHLLINK 2.5.1).
+type ConsoleData struct {
+	// FillAttributes are the foreground and background colors of text.
+	FillAttributes uint16
+	// PopupFillAttributes are the colors of the text of popups, such as the
+	// command history.
+	PopupFillAttributes uint16
+	// ScreenBufferSize and WindowSize are in character cells, while
+	// WindowOrigin is in pixels.
+	ScreenBufferSize ConsoleSize
+	WindowSize       ConsoleSize
+	WindowOrigin     ConsolePosition
+	FullScreen       bool
+	QuickEdit        bool
+	InsertMode       bool
+	// AutoPosition lets the console choose the position of the window,
+	// ignoring WindowOrigin.
+	AutoPosition bool
+	// FontSize is the size of the font in pixels, stored like the other
+	// sizes: the low word is the width, which is zero for vector fonts, and
+	// the high word is the height.
+	FontSize ConsoleSize
+	// FontFamily is the family and pitch of the font, as in the
+	// tmPitchAndFamily field of TEXTMETRIC, e.g. 0x36 for a modern TrueType
+	// font with a fixed pitch.
+	FontFamily uint32
+	// FontWeight is the weight of the font, from 100 to 1000, where 400 is
+	// normal and 700 is bold.
+	FontWeight uint32
+	// FaceName is the name of the font.
+	FaceName string
+	// CursorSize is the height of the cursor as a percentage of the
+	// character cell: 25 or less is small, 50 or less is medium, and more is
+	// large.
+	CursorSize uint32
+	// HistoryBufferSize is the number of commands kept in each history
+	// buffer, and NumberOfHistoryBuffers is the number of buffers, one of
+	// which is used by each process attached to the console. HistoryNoDup
+	// discards commands that duplicate one already in the history.
+	HistoryBufferSize      uint32
+	NumberOfHistoryBuffers uint32
+	HistoryNoDup           bool
+	// ColorTable is the console palette. Each color is stored as 0x00BBGGRR,
+	// that is, red in the lowest byte.
+	ColorTable [16]uint32
+
+	// data is the block as parsed, so that the fields that are not modeled
+	// survive WriteTo
+	data []byte
+}
+
+// ConsoleSize is a size stored as a 32-bit value whose low word is X, the
+// width, and whose high word is Y, the height.
+type ConsoleSize struct {
+	X, Y uint16
+}
+
+// ConsolePosition is a position stored as a 32-bit value whose low word is X
+// and whose high word is Y. Both are signed, as the window may start left of
+// or above the primary monitor.
+type ConsolePosition struct {
+	X, Y int16
+}
+
+// FontIsBold reports whether FontWeight is bold or heavier.
+func (console *ConsoleData) FontIsBold() bool {
+	return console.FontWeight >= 700
+}
+
+// size of the ConsoleDataBlock excluding BlockSize and BlockSignature
+const consoleDataSize = 0xcc - 8
+
+// ConsoleColors returns the palette of the ConsoleDataBlock, or all black if
+// there is none.
+func (lnk *LNK) ConsoleColors() [16]color.RGBA {
+	lnk.LoadExtraData()
+	var colors [16]color.RGBA
+	if lnk.Console == nil {
+		for i := range colors {
+			colors[i].A = 0xff
+		}
+		return colors
+	}
+
+	for i, bgr := range lnk.Console.ColorTable {
+		colors[i] = color.RGBA{
+			R: uint8(bgr),
+			G: uint8(bgr >> 8),
+			B: uint8(bgr >> 16),
+			A: 0xff,
+		}
+	}
+	return colors
+}
+
+func readConsoleData(block []byte) (*ConsoleData, error) {
+	if len(block) != consoleDataSize {
+		return nil, ErrInvalidSize
+	}
+
+	console := &ConsoleData{
+		FillAttributes:      endianness.Uint16(block[0x00:]),
+		PopupFillAttributes: endianness.Uint16(block[0x02:]),
+		ScreenBufferSize: ConsoleSize{
+			X: endianness.Uint16(block[0x04:]),
+			Y: endianness.Uint16(block[0x06:]),
+		},
+		WindowSize: ConsoleSize{
+			X: endianness.Uint16(block[0x08:]),
+			Y: endianness.Uint16(block[0x0a:]),
+		},
+		WindowOrigin: ConsolePosition{
+			X: int16(endianness.Uint16(block[0x0c:])),
+			Y: int16(endianness.Uint16(block[0x0e:])),
+		},
+		FontSize: ConsoleSize{
+			X: endianness.Uint16(block[0x18:]),
+			Y: endianness.Uint16(block[0x1a:]),
+		},
+		FontFamily:             endianness.Uint32(block[0x1c:]),
+		FontWeight:             endianness.Uint32(block[0x20:]),
+		FaceName:               decodeUTF16(block[0x24:0x64]),
+		CursorSize:             endianness.Uint32(block[0x64:]),
+		FullScreen:             endianness.Uint32(block[0x68:]) != 0,
+		QuickEdit:              endianness.Uint32(block[0x6c:]) != 0,
+		InsertMode:             endianness.Uint32(block[0x70:]) != 0,
+		AutoPosition:           endianness.Uint32(block[0x74:]) != 0,
+		HistoryBufferSize:      endianness.Uint32(block[0x78:]),
+		NumberOfHistoryBuffers: endianness.Uint32(block[0x7c:]),
+		HistoryNoDup:           endianness.Uint32(block[0x80:]) != 0,
+		data:                   block,
+	}
+	for i := range console.ColorTable {
+		console.ColorTable[i] = endianness.Uint32(block[0x84+4*i:])
+	}
+	return console, nil
+}
+
+func (console *ConsoleData) bytes() []byte {
+	block := make([]byte, consoleDataSize)
+	copy(block, console.data)
+
+	endianness.PutUint16(block[0x00:], console.FillAttributes)
+	endianness.PutUint16(block[0x02:], console.PopupFillAttributes)
+	endianness.PutUint16(block[0x04:], console.ScreenBufferSize.X)
+	endianness.PutUint16(block[0x06:], console.ScreenBufferSize.Y)
+	endianness.PutUint16(block[0x08:], console.WindowSize.X)
+	endianness.PutUint16(block[0x0a:], console.WindowSize.Y)
+	endianness.PutUint16(block[0x0c:], uint16(console.WindowOrigin.X))
+	endianness.PutUint16(block[0x0e:], uint16(console.WindowOrigin.Y))
+	endianness.PutUint16(block[0x18:], console.FontSize.X)
+	endianness.PutUint16(block[0x1a:], console.FontSize.Y)
+	endianness.PutUint32(block[0x1c:], console.FontFamily)
+	endianness.PutUint32(block[0x20:], console.FontWeight)
+	faceName := encodeUTF16(console.FaceName, 31)
+	for i := 0; i < 32; i++ {
+		var char uint16
+		if i < len(faceName) {
+			char = faceName[i]
+		}
+		endianness.PutUint16(block[0x24+2*i:], char)
+	}
+	endianness.PutUint32(block[0x64:], console.CursorSize)
+	endianness.PutUint32(block[0x68:], boolUint32(console.FullScreen))
+	endianness.PutUint32(block[0x6c:], boolUint32(console.QuickEdit))
+	endianness.PutUint32(block[0x70:], boolUint32(console.InsertMode))
+	endianness.PutUint32(block[0x74:], boolUint32(console.AutoPosition))
+	endianness.PutUint32(block[0x78:], console.HistoryBufferSize)
+	endianness.PutUint32(block[0x7c:], console.NumberOfHistoryBuffers)
+	endianness.PutUint32(block[0x80:], boolUint32(console.HistoryNoDup))
+	for i, bgr := range console.ColorTable {
+		endianness.PutUint32(block[0x84+4*i:], bgr)
+	}
+	return block
+}
+
+func boolUint32(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package lnk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConsoleWindowPlacement(t *testing.T) {
+	lnk := NewBuilder().Build()
+	lnk.Console = &ConsoleData{
+		ScreenBufferSize: ConsoleSize{120, 9001},
+		WindowSize:       ConsoleSize{120, 30},
+		WindowOrigin:     ConsolePosition{-8, 100},
+	}
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the low word of each coordinate is X
+	i := bytes.Index(data, []byte{0x02, 0x00, 0x00, 0xa0})
+	if i == -1 {
+		t.Fatal("no ConsoleDataBlock")
+	}
+	want := []byte{120, 0, 0x29, 0x23, 120, 0, 30, 0, 0xf8, 0xff, 100, 0}
+	if got := data[i+8 : i+20]; !bytes.Equal(got, want) {
+		t.Errorf("coordinates = % x, want % x", got, want)
+	}
+
+	parsed, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Console == nil {
+		t.Fatal("Console = nil")
+	}
+	if parsed.Console.WindowOrigin != lnk.Console.WindowOrigin || parsed.Console.WindowSize != lnk.Console.WindowSize || parsed.Console.ScreenBufferSize != lnk.Console.ScreenBufferSize {
+		t.Errorf("Console = %+v, want %+v", parsed.Console, lnk.Console)
+	}
+}
+
+func TestConsoleFont(t *testing.T) {
+	lnk := NewBuilder().Build()
+	lnk.Console = &ConsoleData{
+		FontSize:   ConsoleSize{0, 16},
+		FontFamily: 0x36,
+		FontWeight: 700,
+		FaceName:   "Consolas",
+		CursorSize: 25,
+	}
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the height is in the high word
+	i := bytes.Index(data, []byte{0x02, 0x00, 0x00, 0xa0})
+	if i == -1 {
+		t.Fatal("no ConsoleDataBlock")
+	}
+	if got := data[i+4+0x18 : i+4+0x1c]; !bytes.Equal(got, []byte{0, 0, 16, 0}) {
+		t.Errorf("FontSize = % x, want 00 00 10 00", got)
+	}
+
+	parsed, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if console := parsed.Console; console.FontSize != lnk.Console.FontSize || console.FontFamily != 0x36 || console.FontWeight != 700 || console.FaceName != "Consolas" || console.CursorSize != 25 {
+		t.Errorf("Console = %+v, want %+v", console, lnk.Console)
+	}
+	if !parsed.Console.FontIsBold() {
+		t.Error("FontIsBold() = false for weight 700")
+	}
+	if console := load(t, "console.lnk").Console; console.FontIsBold() || console.FontSize.Y != 16 || console.FaceName != "Consolas" {
+		t.Errorf("Console = %+v, want a regular 16px Consolas", console)
+	}
+}
+
+func TestConsoleHistoryAndPopup(t *testing.T) {
+	console := load(t, "console.lnk").Console
+	if console == nil {
+		t.Fatal("Console = nil")
+	}
+	if console.FillAttributes != 0x07 || console.PopupFillAttributes != 0xf5 {
+		t.Errorf("FillAttributes = 0x%x, PopupFillAttributes = 0x%x, want 0x7, 0xf5", console.FillAttributes, console.PopupFillAttributes)
+	}
+	if console.HistoryBufferSize != 50 || console.NumberOfHistoryBuffers != 4 || console.HistoryNoDup {
+		t.Errorf("HistoryBufferSize = %d, NumberOfHistoryBuffers = %d, HistoryNoDup = %v, want 50, 4, false", console.HistoryBufferSize, console.NumberOfHistoryBuffers, console.HistoryNoDup)
+	}
+	if !console.QuickEdit || !console.InsertMode || !console.AutoPosition || console.FullScreen {
+		t.Errorf("QuickEdit = %v, InsertMode = %v, AutoPosition = %v, FullScreen = %v", console.QuickEdit, console.InsertMode, console.AutoPosition, console.FullScreen)
+	}
+
+	// each boolean is read from its own field
+	lnk := NewBuilder().Build()
+	lnk.Console = &ConsoleData{FullScreen: true, HistoryNoDup: true}
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := parsed.Console; !c.FullScreen || !c.HistoryNoDup || c.QuickEdit || c.InsertMode || c.AutoPosition {
+		t.Errorf("Console = %+v, want only FullScreen and HistoryNoDup", c)
+	}
+}
@@ -0,0 +1,110 @@
+package lnk
+
+import "strings"
+
+// DarwinData is the DarwinDataBlock, which specifies the Windows Installer
+// application that an advertised shortcut launches (MS-SHLLINK 2.5.3).
+type DarwinData struct {
+	DescriptorANSI    string
+	DescriptorUnicode string
+}
+
+// Descriptor returns DescriptorUnicode, or DescriptorANSI if it is empty.
+func (darwin *DarwinData) Descriptor() string {
+	if darwin.DescriptorUnicode != "" {
+		return darwin.DescriptorUnicode
+	}
+	return darwin.DescriptorANSI
+}
+
+func readDarwinData(block []byte) (*DarwinData, error) {
+	env, err := readEnvironmentData(block)
+	if err != nil {
+		return nil, err
+	}
+	return &DarwinData{env.TargetANSI, env.TargetUnicode}, nil
+}
+
+func (darwin *DarwinData) bytes() []byte {
+	env := EnvironmentVariableData{darwin.DescriptorANSI, darwin.DescriptorUnicode}
+	return env.bytes()
+}
+
+// MSIDescriptor is a Windows Installer descriptor, which identifies the
+// component of a product that an advertised shortcut launches, and the feature
+// that is installed on demand to provide it.
+type MSIDescriptor struct {
+	// ProductCode and ComponentCode are in registry form, e.g.
+	// "{90110409-6000-11D3-8CFE-0150048383C9}". ComponentCode is empty if
+	// the descriptor omits it, which it does when the feature has a single
+	// component.
+	ProductCode   string
+	FeatureName   string
+	ComponentCode string
+}
+
+// the characters of the base 85 encoding of GUIDs in descriptors, in order of
+// value
+const base85Alphabet = "!$%&'()*+,-.0123456789=?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[]^_`abcdefghijklmnopqrstuvwxyz{}~"
+
+// MSIDescriptor decodes the descriptor in the DarwinDataBlock, as
+// MsiDecomposeDescriptor does. ok is false if there is no DarwinDataBlock or
+// its descriptor is malformed.
+//
+// A descriptor is the product code, followed by the feature name, and then
+// either '>' and the component code, or '<' if the component code is
+// omitted. The codes are GUIDs packed into 20 characters: each group of 5
+// characters encodes one of the 4 little-endian 32-bit words of the GUID in
+// its on-disk byte order, in base 85 with the least significant digit first.
+func (lnk *LNK) MSIDescriptor() (descriptor MSIDescriptor, ok bool) {
+	lnk.LoadExtraData()
+	if lnk.Darwin == nil {
+		return MSIDescriptor{}, false
+	}
+	str := lnk.Darwin.Descriptor()
+
+	product, ok := decodeBase85GUID(str)
+	if !ok {
+		return MSIDescriptor{}, false
+	}
+	descriptor.ProductCode = product.String()
+	str = str[20:]
+
+	end := strings.IndexAny(str, "<>")
+	if end == -1 {
+		return MSIDescriptor{}, false
+	}
+	descriptor.FeatureName = str[:end]
+	if str[end] == '<' {
+		return descriptor, true
+	}
+
+	component, ok := decodeBase85GUID(str[end+1:])
+	if !ok {
+		return MSIDescriptor{}, false
+	}
+	descriptor.ComponentCode = component.String()
+	return descriptor, true
+}
+
+// decodeBase85GUID decodes a GUID packed into the first 20 characters of str.
+func decodeBase85GUID(str string) (id GUID, ok bool) {
+	if len(str) < 20 {
+		return id, false
+	}
+	for word := 0; word < 4; word++ {
+		var value uint64
+		for i := 4; i >= 0; i-- {
+			digit := strings.IndexByte(base85Alphabet, str[5*word+i])
+			if digit == -1 {
+				return id, false
+			}
+			value = value*85 + uint64(digit)
+		}
+		if value > 0xffffffff {
+			return id, false
+		}
+		endianness.PutUint32(id[4*word:], uint32(value))
+	}
+	return id, true
+}
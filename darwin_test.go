@@ -0,0 +1,61 @@
+package lnk
+
+import "testing"
+
+// encodeBase85GUID packs id into 20 characters, as descriptors do.
+func encodeBase85GUID(id GUID) string {
+	var str []byte
+	for word := 0; word < 4; word++ {
+		value := endianness.Uint32(id[4*word:])
+		for i := 0; i < 5; i++ {
+			str = append(str, base85Alphabet[value%85])
+			value /= 85
+		}
+	}
+	return string(str)
+}
+
+func TestMSIDescriptor(t *testing.T) {
+	product := guid("90110409-6000-11D3-8CFE-0150048383C9")
+	component := guid("12345678-9ABC-DEF0-1234-56789ABCDEF0")
+
+	tests := []struct {
+		descriptor string
+		want       MSIDescriptor
+		ok         bool
+	}{
+		// Excel 2007
+		{"w_1^VX!!!!!!!!!MKKSkEXCELFiles>tW{~$4Q]c@II=l2xaTO5Z", MSIDescriptor{
+			ProductCode:   "{91120000-0030-0000-0000-0000000FF1CE}",
+			FeatureName:   "EXCELFiles",
+			ComponentCode: "{0638C49D-BB8B-4CD1-B191-052E8F325736}",
+		}, true},
+		{encodeBase85GUID(product) + "ExcelFiles>" + encodeBase85GUID(component), MSIDescriptor{
+			ProductCode:   "{90110409-6000-11D3-8CFE-0150048383C9}",
+			FeatureName:   "ExcelFiles",
+			ComponentCode: "{12345678-9ABC-DEF0-1234-56789ABCDEF0}",
+		}, true},
+		{encodeBase85GUID(product) + "Feature<", MSIDescriptor{
+			ProductCode: "{90110409-6000-11D3-8CFE-0150048383C9}",
+			FeatureName: "Feature",
+		}, true},
+		{encodeBase85GUID(product) + "Feature", MSIDescriptor{}, false},
+		{encodeBase85GUID(product) + "Feature>short", MSIDescriptor{}, false},
+		{"short", MSIDescriptor{}, false},
+		// a character outside the alphabet
+		{"\"" + encodeBase85GUID(product)[1:] + "F<", MSIDescriptor{}, false},
+		// a word that overflows 32 bits
+		{"~~~~~" + encodeBase85GUID(product)[5:] + "F<", MSIDescriptor{}, false},
+	}
+	for _, test := range tests {
+		lnk := &LNK{Darwin: &DarwinData{DescriptorUnicode: test.descriptor}}
+		got, ok := lnk.MSIDescriptor()
+		if got != test.want || ok != test.ok {
+			t.Errorf("MSIDescriptor() of %q = %+v, %v, want %+v, %v", test.descriptor, got, ok, test.want, test.ok)
+		}
+	}
+
+	if _, ok := load(t, "local.lnk").MSIDescriptor(); ok {
+		t.Error("MSIDescriptor() succeeded without a DarwinDataBlock")
+	}
+}
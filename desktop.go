@@ -0,0 +1,85 @@
+package lnk
+
+import "strings"
+
+// ToDesktopEntry returns the contents of a freedesktop.org .desktop file that
+// launches the target of the shortcut, for migrating a Windows menu to a Linux
+// desktop. Paths are converted as for CreateSymlink: translate converts the
+// path returned by ResolveTarget, WorkingDir and the file of the icon to paths
+// on this system, and returns an empty string for those that cannot be
+// translated.
+//
+// The Name key is the description of the shortcut, or else the file name of
+// the target without its extension. Exec holds the translated target followed
+// by ArgumentList, quoted and escaped as the Desktop Entry Specification
+// requires; the arguments are not translated. Path and Icon are omitted if
+// their paths cannot be translated.
+//
+// ErrNotFileSystemTarget is returned if the target is not a file system path
+// or cannot be translated.
+func (lnk *LNK) ToDesktopEntry(translate func(winPath string) string) (string, error) {
+	path, err := lnk.ResolveTarget()
+	if err != nil {
+		return "", err
+	}
+	if !isFileSystemPath(path) {
+		return "", ErrNotFileSystemTarget
+	}
+	target := translate(path)
+	if target == "" {
+		return "", ErrNotFileSystemTarget
+	}
+
+	name := lnk.Name
+	if name == "" {
+		name = symlinkName(path)
+	}
+	exec := []string{quoteExecArgument(target)}
+	for _, arg := range lnk.ArgumentList() {
+		exec = append(exec, quoteExecArgument(arg))
+	}
+
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	b.WriteString("Name=" + escapeDesktopValue(name) + "\n")
+	b.WriteString("Exec=" + escapeDesktopValue(strings.Join(exec, " ")) + "\n")
+	if lnk.WorkingDir != "" {
+		if dir := translate(lnk.WorkingDir); dir != "" {
+			b.WriteString("Path=" + escapeDesktopValue(dir) + "\n")
+		}
+	}
+	if icon, _, ok := lnk.Icon(); ok {
+		if icon := translate(icon); icon != "" {
+			b.WriteString("Icon=" + escapeDesktopValue(icon) + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// quoteExecArgument quotes an argument of the Exec key if it contains
+// reserved characters, escaping '"', '`', '$' and '\' within the quotes, and
+// doubles '%', which introduces field codes.
+func quoteExecArgument(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`=") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range arg {
+		if strings.ContainsRune("\"`$\\", c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// escapeDesktopValue escapes a value of type string, in which backslashes,
+// newlines, tabs and carriage returns must be escaped.
+func escapeDesktopValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(value)
+}
@@ -0,0 +1,68 @@
+package lnk
+
+import "testing"
+
+func TestToDesktopEntry(t *testing.T) {
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\Program Files\App\app.exe`); err != nil {
+		t.Fatal(err)
+	}
+	b.SetArguments(`--open "my file.txt" 100%`)
+	b.SetWorkingDir(`C:\Work`)
+	b.SetIconLocation(`C:\Program Files\App\app.ico`, 0)
+	lnk := roundTrip(t, b)
+
+	got, err := lnk.ToDesktopEntry(translateMnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[Desktop Entry]\n" +
+		"Type=Application\n" +
+		"Name=app\n" +
+		`Exec="/mnt/c/Program Files/App/app.exe" --open "my file.txt" 100%%` + "\n" +
+		"Path=/mnt/c/Work\n" +
+		"Icon=/mnt/c/Program Files/App/app.ico\n"
+	if got != want {
+		t.Errorf("ToDesktopEntry() =\n%s\nwant:\n%s", got, want)
+	}
+
+	// the description names the entry, and untranslated paths are omitted
+	b.SetName("My App")
+	b.SetWorkingDir(`\\server\share`)
+	got, err = roundTrip(t, b).ToDesktopEntry(translateMnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "[Desktop Entry]\n" +
+		"Type=Application\n" +
+		"Name=My App\n" +
+		`Exec="/mnt/c/Program Files/App/app.exe" --open "my file.txt" 100%%` + "\n" +
+		"Icon=/mnt/c/Program Files/App/app.ico\n"
+	if got != want {
+		t.Errorf("ToDesktopEntry() =\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := load(t, "unc.lnk").ToDesktopEntry(translateMnt); err != ErrNotFileSystemTarget {
+		t.Errorf("ToDesktopEntry() error = %v, want %v", err, ErrNotFileSystemTarget)
+	}
+}
+
+func TestQuoteExecArgument(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"plain", "plain"},
+		{"", `""`},
+		{"my file", `"my file"`},
+		{`a\b`, `"a\\b"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"$HOME", `"\$HOME"`},
+		{"100%", "100%%"},
+	}
+	for _, test := range tests {
+		if got := quoteExecArgument(test.arg); got != test.want {
+			t.Errorf("quoteExecArgument(%q) = %q, want %q", test.arg, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package lnk
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Detect reports the format of the data in r without parsing it, so that
+// files can be routed to the right parser regardless of their extension:
+//
+//   - "lnk" for shortcuts, which start with a HeaderSize of 76 and the
+//     LinkCLSID
+//   - "url" for Internet shortcuts, which are INI files starting with an
+//     [InternetShortcut] section
+//   - "cfb" for compound files, which ParseCFBStream reads shortcuts from
+//   - "" for anything else, including input that is too short
+//
+// If r is a *bufio.Reader, the bytes are peeked rather than consumed.
+// Otherwise, up to the first 32 bytes of r are consumed.
+func Detect(r io.Reader) (format string, err error) {
+	file, ok := r.(*bufio.Reader)
+	if !ok {
+		file = bufio.NewReaderSize(r, 32)
+	}
+
+	data, err := file.Peek(32)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+
+	if len(data) >= 20 && endianness.Uint32(data) == 76 && bytes.Equal(data[4:20], validCLSID[:]) {
+		return "lnk", nil
+	}
+	if bytes.HasPrefix(data, cfbSignature) {
+		return "cfb", nil
+	}
+	section := []byte("[InternetShortcut]")
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) >= len(section) && bytes.EqualFold(data[:len(section)], section) {
+		return "url", nil
+	}
+	return "", nil
+}
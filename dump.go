@@ -0,0 +1,211 @@
+package lnk
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DumpOptions controls the output of Dump.
+type DumpOptions struct {
+	// Color highlights section headings with ANSI escape codes.
+	Color bool
+	// IDListHex includes a hex dump of IDListBytes.
+	IDListHex bool
+	// UTC prints timestamps in UTC rather than in the local time zone.
+	UTC bool
+}
+
+// Dump writes a human-readable report of the shortcut to w, grouped into
+// Header, Target, StringData, LinkInfo and ExtraData sections with aligned
+// values. Fields that are not present in the file are omitted.
+func (lnk *LNK) Dump(w io.Writer, opts DumpOptions) error {
+	err := lnk.LoadExtraData()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	section := func(name string) {
+		if opts.Color {
+			name = "\x1b[1m" + name + "\x1b[0m"
+		}
+		fmt.Fprintln(tw, name)
+	}
+	field := func(name string, value interface{}) {
+		fmt.Fprintf(tw, "  %s:\t%v\n", name, value)
+	}
+	timestamp := func(t time.Time) string {
+		if opts.UTC {
+			t = t.UTC()
+		} else {
+			t = t.Local()
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	section("Header")
+	field("Link flags", strings.Join(lnk.linkFlagNames(), " "))
+	field("File attributes", strings.Join(lnk.fileAttributeNames(), " "))
+	field("Creation time", timestamp(lnk.CreationTime))
+	field("Access time", timestamp(lnk.AccessTime))
+	field("Write time", timestamp(lnk.WriteTime))
+	field("File size", lnk.FileSize)
+	field("Icon index", lnk.IconIndex)
+	field("Show command", lnk.ShowCommandString())
+	if lnk.HotKey.Key != 0 {
+		field("Hot key", lnk.HotKey)
+	}
+
+	section("Target")
+	field("IDList size", len(lnk.IDListBytes))
+	if items, err := lnk.ItemIDsWithOptions(IDListOptions{Lenient: true}); len(lnk.IDListBytes) != 0 {
+		if err != nil {
+			field("ItemIDs", fmt.Sprintf("%d (%v)", len(items), err))
+		} else {
+			field("ItemIDs", len(items))
+		}
+	}
+	if opts.IDListHex && len(lnk.IDListBytes) != 0 {
+		fmt.Fprint(tw, indent(hex.Dump(lnk.IDListBytes), "    "))
+	}
+
+	section("StringData")
+	if lnk.HasName {
+		field("Name", lnk.Name)
+	}
+	if lnk.HasRelativePath {
+		field("Relative path", lnk.RelativePath)
+	}
+	if lnk.HasWorkingDir {
+		field("Working dir", lnk.WorkingDir)
+	}
+	if lnk.HasArguments {
+		field("Arguments", lnk.Arguments)
+	}
+	if lnk.HasIconLocation {
+		field("Icon location", lnk.IconLocation)
+	}
+
+	section("LinkInfo")
+	if lnk.LinkInfoSize != 0 {
+		field("Size", lnk.LinkInfoSize)
+	}
+	if lnk.VolumeIDAndLocalBasePath {
+		field("Drive type", lnk.DriveType)
+		field("Drive serial number", lnk.DriveSerial())
+		field("Volume label", lnk.VolumeLabel)
+		field("Local base path", lnk.LocalBasePath)
+	}
+	if lnk.CommonNetworkRelativeLinkAndPathSuffix {
+		field("Net name", lnk.NetName)
+		if lnk.ValidDevice {
+			field("Device name", lnk.DeviceName)
+		}
+		if lnk.ValidNetType {
+			field("Network provider type", fmt.Sprintf("0x%08x", lnk.NetworkProviderType))
+		}
+	}
+	if lnk.CommonPathSuffix != "" {
+		field("Common path suffix", lnk.CommonPathSuffix)
+	}
+
+	section("ExtraData")
+	if names := lnk.ExtraBlockNames(); len(names) != 0 {
+		field("Blocks", strings.Join(names, " "))
+	}
+	if lnk.EnvironmentVariable != nil {
+		field("Environment target", lnk.EnvironmentVariable.Target())
+	}
+	if lnk.IconEnvironment != nil {
+		field("Icon environment", lnk.IconEnvironment.Target())
+	}
+	if lnk.Console != nil {
+		field("Console font", lnk.Console.FaceName)
+	}
+	if lnk.Tracker != nil {
+		field("Machine ID", lnk.Tracker.MachineID)
+	}
+	if lnk.Darwin != nil {
+		field("Darwin descriptor", lnk.Darwin.Descriptor())
+	}
+	if lnk.SpecialFolder != nil {
+		field("Special folder", fmt.Sprintf("%s (CSIDL %d, offset %d)", lnk.SpecialFolder.Name, lnk.SpecialFolder.ID, lnk.SpecialFolder.Offset))
+	}
+	if lnk.KnownFolder != nil {
+		field("Known folder", fmt.Sprintf("%s (%s, offset %d)", lnk.KnownFolder.Name, lnk.KnownFolder.ID, lnk.KnownFolder.Offset))
+	}
+	if id, ok := lnk.AppUserModelID(); ok {
+		field("AppUserModelID", id)
+	}
+	if len(lnk.PropertyStore) != 0 {
+		field("Properties", len(lnk.PropertyStore))
+	}
+
+	return tw.Flush()
+}
+
+// linkFlagNames returns the names of the LinkFlags that are set, for Dump and
+// MarshalXML. They are the names of the fields, and so of the JSON keys, which
+// spell RunInSeperateProcess as it has always been spelled here rather than as
+// MS-SHLLINK spells it.
+func (lnk *LNK) linkFlagNames() []string {
+	return setNames([]bool{
+		lnk.HasLinkInfo, lnk.HasName, lnk.HasRelativePath, lnk.HasWorkingDir,
+		lnk.HasArguments, lnk.HasIconLocation, lnk.IsUnicode, lnk.ForceNoLinkInfo,
+		lnk.HasExpString, lnk.RunInSeperateProcess, lnk.Unused1, lnk.HasDarwinID,
+		lnk.RunAsUser, lnk.HasExpIcon, lnk.NoPidlAlias, lnk.Unused2,
+		lnk.RunWithShimLayer, lnk.ForceNoLinkTrack,
+		lnk.EnableTargetMetadata, lnk.DisableLinkPathTracking,
+		lnk.DisableKnownFolderTracking, lnk.DisableKnownFolderAlias,
+		lnk.AllowLinkToLink, lnk.UnaliasOnSave, lnk.PreferEnvironmentPath,
+		lnk.KeepLocalIDListForUNCTarget,
+	}, []string{
+		"HasLinkInfo", "HasName", "HasRelativePath", "HasWorkingDir",
+		"HasArguments", "HasIconLocation", "IsUnicode", "ForceNoLinkInfo",
+		"HasExpString", "RunInSeperateProcess", "Unused1", "HasDarwinID",
+		"RunAsUser", "HasExpIcon", "NoPidlAlias", "Unused2",
+		"RunWithShimLayer", "ForceNoLinkTrack",
+		"EnableTargetMetadata", "DisableLinkPathTracking",
+		"DisableKnownFolderTracking", "DisableKnownFolderAlias",
+		"AllowLinkToLink", "UnaliasOnSave", "PreferEnvironmentPath",
+		"KeepLocalIDListForUNCTarget",
+	})
+}
+
+func (lnk *LNK) fileAttributeNames() []string {
+	return setNames([]bool{
+		lnk.ReadOnly, lnk.Hidden, lnk.System, lnk.Directory, lnk.Archive,
+		lnk.Normal, lnk.Temporary, lnk.SparseFile, lnk.ReparsePoint,
+		lnk.Compressed, lnk.Offline, lnk.NotContentIndexed, lnk.Encrypted,
+	}, []string{
+		"ReadOnly", "Hidden", "System", "Directory", "Archive",
+		"Normal", "Temporary", "SparseFile", "ReparsePoint",
+		"Compressed", "Offline", "NotContentIndexed", "Encrypted",
+	})
+}
+
+// setNames returns the names whose corresponding flags are set.
+func setNames(flags []bool, names []string) []string {
+	var set []string
+	for i, flag := range flags {
+		if flag {
+			set = append(set, names[i])
+		}
+	}
+	return set
+}
+
+// indent prefixes every line of str.
+func indent(str, prefix string) string {
+	lines := strings.SplitAfter(str, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,384 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ExtraData block signatures (MS-SHLLINK 2.5).
+const (
+	EnvironmentVariableDataBlockSignature = 0xa0000001
+	ConsoleDataBlockSignature             = 0xa0000002
+	TrackerDataBlockSignature             = 0xa0000003
+	ConsoleFEDataBlockSignature           = 0xa0000004
+	SpecialFolderDataBlockSignature       = 0xa0000005
+	DarwinDataBlockSignature              = 0xa0000006
+	IconEnvironmentDataBlockSignature     = 0xa0000007
+	ShimDataBlockSignature                = 0xa0000008
+	PropertyStoreDataBlockSignature       = 0xa0000009
+	KnownFolderDataBlockSignature         = 0xa000000b
+	VistaAndAboveIDListDataBlockSignature = 0xa000000c
+)
+
+// blockNames maps ExtraData block signatures to the names of the blocks.
+var blockNames = map[uint32]string{
+	EnvironmentVariableDataBlockSignature: "EnvironmentVariableDataBlock",
+	ConsoleDataBlockSignature:             "ConsoleDataBlock",
+	TrackerDataBlockSignature:             "TrackerDataBlock",
+	ConsoleFEDataBlockSignature:           "ConsoleFEDataBlock",
+	SpecialFolderDataBlockSignature:       "SpecialFolderDataBlock",
+	DarwinDataBlockSignature:              "DarwinDataBlock",
+	IconEnvironmentDataBlockSignature:     "IconEnvironmentDataBlock",
+	ShimDataBlockSignature:                "ShimDataBlock",
+	PropertyStoreDataBlockSignature:       "PropertyStoreDataBlock",
+	KnownFolderDataBlockSignature:         "KnownFolderDataBlock",
+	VistaAndAboveIDListDataBlockSignature: "VistaAndAboveIDListDataBlock",
+}
+
+// ExtraDataBlock is an ExtraData block whose signature is not known.
+type ExtraDataBlock struct {
+	Signature uint32
+	// Data excludes the BlockSize and BlockSignature fields.
+	Data []byte
+}
+
+// EnvironmentVariableData is the EnvironmentVariableDataBlock, which specifies
+// the target as a path containing environment variables, such as
+// `%windir%\notepad.exe` (MS-SHLLINK 2.5.4). The IconEnvironmentDataBlock has
+// the same layout and specifies the path of the icon instead (MS-SHLLINK
+// 2.5.5).
+type EnvironmentVariableData struct {
+	TargetANSI    string
+	TargetUnicode string
+}
+
+// Target returns TargetUnicode, or TargetANSI if it is empty.
+func (env *EnvironmentVariableData) Target() string {
+	if env.TargetUnicode != "" {
+		return env.TargetUnicode
+	}
+	return env.TargetANSI
+}
+
+// SpecialFolderData is the SpecialFolderDataBlock, which specifies the
+// location of a special folder within the IDList (MS-SHLLINK 2.5.9).
+type SpecialFolderData struct {
+	// ID is the CSIDL of the folder.
+	ID uint32
+	// Offset is the offset into the IDList of the first child segment of
+	// the folder.
+	Offset uint32
+	// Name is the canonical name of the folder, or empty if it is unknown.
+	Name string
+}
+
+// KnownFolderData is the KnownFolderDataBlock, which specifies the location of
+// a known folder within the IDList (MS-SHLLINK 2.5.6).
+type KnownFolderData struct {
+	// ID is the KNOWNFOLDERID of the folder.
+	ID GUID
+	// Offset is the offset into the IDList of the first child segment of
+	// the folder.
+	Offset uint32
+	// Name is the canonical name of the folder, or empty if it is unknown.
+	Name string
+}
+
+// blockDecoders holds the decoders registered with RegisterBlockDecoder.
+var blockDecoders = make(map[uint32]func([]byte) (interface{}, error))
+
+// RegisterBlockDecoder registers a decoder for the ExtraData blocks with the
+// given signature that this package does not decode, such as proprietary
+// blocks. The data of each block, excluding the BlockSize and BlockSignature
+// fields, is passed to fn, and the value it returns is stored in
+// LNK.DecodedBlocks, while an error it returns fails the parse. Blocks that
+// are neither known nor decoded by this package are kept in UnknownBlocks as
+// well, so they are still written by WriteTo.
+//
+// Registration is not synchronized with parsing, so decoders should be
+// registered before any shortcut is parsed, such as in an init function. A
+// nil fn unregisters the decoder.
+func RegisterBlockDecoder(signature uint32, fn func([]byte) (interface{}, error)) {
+	if fn == nil {
+		delete(blockDecoders, signature)
+		return
+	}
+	blockDecoders[signature] = fn
+}
+
+// lazyExtraData is ExtraData whose decoding was deferred by ParseOptions.Lazy.
+type lazyExtraData struct {
+	once   sync.Once
+	data   []byte
+	offset int64
+	opts   ParseOptions
+	err    error
+}
+
+// LoadExtraData decodes ExtraData that was deferred by ParseOptions.Lazy into
+// the fields of the LNK, and returns the error that decoding it returned.
+// Decoding uses the options the shortcut was parsed with, so irregularities
+// are only errors if ParseOptions.Strict was set. Only the first call decodes
+// it, so it may be called concurrently, but the fields must not be accessed
+// concurrently with the first call. It does nothing for shortcuts that were
+// not parsed lazily.
+//
+// The methods that need ExtraData, such as AppUserModelID, call it
+// themselves.
+func (lnk *LNK) LoadExtraData() error {
+	if lnk.lazy == nil {
+		return nil
+	}
+	lnk.lazy.once.Do(func() {
+		lnk.lazy.err = readExtraData(bytes.NewReader(lnk.lazy.data), lnk, lnk.lazy.opts, lnk.lazy.offset)
+	})
+	return lnk.lazy.err
+}
+
+// ExtraBlocks returns the signatures of every ExtraData block in the file,
+// known or not, in the order in which they are stored.
+func (lnk *LNK) ExtraBlocks() []uint32 {
+	lnk.LoadExtraData()
+	return lnk.extraBlocks
+}
+
+// ExtraBlockNames returns the names of the blocks returned by ExtraBlocks.
+// Blocks with unknown signatures are named by their signature in hex.
+func (lnk *LNK) ExtraBlockNames() []string {
+	lnk.LoadExtraData()
+	names := make([]string, len(lnk.extraBlocks))
+	for i, signature := range lnk.extraBlocks {
+		name, ok := blockNames[signature]
+		if !ok {
+			name = fmt.Sprintf("0x%08x", signature)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// HasUnknownBlocks reports whether the shortcut carries ExtraData blocks whose
+// signatures are not defined by MS-SHLLINK. Such blocks are kept in
+// UnknownBlocks.
+func (lnk *LNK) HasUnknownBlocks() bool {
+	lnk.LoadExtraData()
+	return len(lnk.UnknownBlocks) != 0
+}
+
+// VendorBlocks returns the unknown blocks whose signatures are outside the
+// 0xA000xxxx range used by Microsoft, which are likely to have been added by a
+// third-party tool and are worth flagging.
+func (lnk *LNK) VendorBlocks() []ExtraDataBlock {
+	lnk.LoadExtraData()
+	var blocks []ExtraDataBlock
+	for _, block := range lnk.UnknownBlocks {
+		if block.Signature&0xffff0000 != 0xa0000000 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// readExtraData reads ExtraData blocks until the TerminalBlock. Known blocks
+// that are not decoded are skipped, and unknown blocks are kept. offset is
+// the offset of the ExtraData in the shortcut, for errors.
+func readExtraData(r io.Reader, lnk *LNK, opts ParseOptions, offset int64) error {
+	file := &countingReader{r: r}
+	for {
+		blockOffset := offset + file.n
+		var blockSize uint32
+		err := binary.Read(file, endianness, &blockSize)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return lnk.missingTerminalBlock(opts.Strict, blockOffset)
+		}
+		if err != nil {
+			return err
+		}
+
+		// TerminalBlock
+		if blockSize < 0x04 {
+			return nil
+		}
+		if blockSize < 0x08 {
+			return malformed(SectionExtraData, blockOffset, fmt.Sprintf("BlockSize 0x%x is too small", blockSize), ErrInvalidSize)
+		}
+		if err := opts.checkSize(SectionExtraData, blockOffset, "BlockSize", blockSize); err != nil {
+			return err
+		}
+
+		var signature uint32
+		err = binary.Read(file, endianness, &signature)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return lnk.truncatedBlock(opts.Strict, blockOffset)
+		}
+		if err != nil {
+			return err
+		}
+
+		block, err := readBytes(file, int64(blockSize)-8)
+		if err == io.ErrUnexpectedEOF {
+			return lnk.truncatedBlock(opts.Strict, blockOffset)
+		}
+		if err != nil {
+			return err
+		}
+
+		lnk.extraBlocks = append(lnk.extraBlocks, signature)
+		invalid := func(err error) error {
+			return malformed(SectionExtraData, blockOffset, fmt.Sprintf("decoding ExtraData block 0x%08x: %v", signature, err), err)
+		}
+
+		switch signature {
+		case EnvironmentVariableDataBlockSignature:
+			lnk.EnvironmentVariable, err = readEnvironmentData(block)
+			if err != nil {
+				return invalid(err)
+			}
+		case IconEnvironmentDataBlockSignature:
+			lnk.IconEnvironment, err = readEnvironmentData(block)
+			if err != nil {
+				return invalid(err)
+			}
+		case ConsoleDataBlockSignature:
+			lnk.Console, err = readConsoleData(block)
+			if err != nil {
+				return invalid(err)
+			}
+		case DarwinDataBlockSignature:
+			lnk.Darwin, err = readDarwinData(block)
+			if err != nil {
+				return invalid(err)
+			}
+		case VistaAndAboveIDListDataBlockSignature:
+			lnk.VistaAndAboveIDList = block
+		case TrackerDataBlockSignature:
+			lnk.Tracker, err = readTrackerData(block)
+			if err != nil {
+				return invalid(err)
+			}
+		case SpecialFolderDataBlockSignature:
+			if blockSize != 0x10 {
+				return invalid(ErrInvalidSize)
+			}
+			lnk.SpecialFolder = &SpecialFolderData{
+				ID:     endianness.Uint32(block),
+				Offset: endianness.Uint32(block[4:]),
+			}
+			lnk.SpecialFolder.Name, _ = LookupCSIDL(lnk.SpecialFolder.ID)
+		case KnownFolderDataBlockSignature:
+			if blockSize != 0x1c {
+				return invalid(ErrInvalidSize)
+			}
+			lnk.KnownFolder = &KnownFolderData{
+				Offset: endianness.Uint32(block[16:]),
+			}
+			copy(lnk.KnownFolder.ID[:], block)
+			lnk.KnownFolder.Name, _ = LookupKnownFolder(lnk.KnownFolder.ID)
+		case PropertyStoreDataBlockSignature:
+			lnk.PropertyStore, err = readPropertyStore(block)
+			if err != nil {
+				return invalid(err)
+			}
+			for i, prop := range lnk.PropertyStore {
+				if t, ok := prop.Value.(time.Time); ok {
+					lnk.PropertyStore[i].Value = lnk.inLocation(t)
+				}
+			}
+		default:
+			if _, ok := blockNames[signature]; !ok {
+				lnk.UnknownBlocks = append(lnk.UnknownBlocks, ExtraDataBlock{signature, block})
+			}
+			if decode, ok := blockDecoders[signature]; ok {
+				value, err := decode(block)
+				if err != nil {
+					return invalid(err)
+				}
+				if lnk.DecodedBlocks == nil {
+					lnk.DecodedBlocks = make(map[uint32]interface{})
+				}
+				lnk.DecodedBlocks[signature] = value
+			}
+		}
+	}
+}
+
+// skipExtraData reads ExtraData blocks up to and including the TerminalBlock
+// without decoding them. A TerminalBlock is appended if it is missing and
+// opts.Strict is not set, in place of the last block if that is truncated.
+func skipExtraData(file io.Reader, lnk *LNK, opts ParseOptions, offset int64) ([]byte, error) {
+	var data []byte
+	for {
+		blockStart := len(data)
+		var blockSize uint32
+		err := binary.Read(file, endianness, &blockSize)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return endianness.AppendUint32(data, 0), lnk.missingTerminalBlock(opts.Strict, offset+int64(len(data)))
+		}
+		if err != nil {
+			return data, err
+		}
+		data = endianness.AppendUint32(data, blockSize)
+
+		// TerminalBlock
+		if blockSize < 0x04 {
+			return data, nil
+		}
+		if blockSize < 0x08 {
+			return data, malformed(SectionExtraData, offset+int64(blockStart), fmt.Sprintf("BlockSize 0x%x is too small", blockSize), ErrInvalidSize)
+		}
+		if err := opts.checkSize(SectionExtraData, offset+int64(blockStart), "BlockSize", blockSize); err != nil {
+			return data, err
+		}
+
+		block, err := readBytes(file, int64(blockSize)-4)
+		if err == io.ErrUnexpectedEOF {
+			return endianness.AppendUint32(data[:blockStart], 0), lnk.truncatedBlock(opts.Strict, offset+int64(blockStart))
+		}
+		data = append(data, block...)
+		if err != nil {
+			return data, err
+		}
+	}
+}
+
+// missingTerminalBlock handles ExtraData that ends where a block or the
+// TerminalBlock should begin, which many shortcuts written by third-party
+// tools do.
+func (lnk *LNK) missingTerminalBlock(strict bool, offset int64) error {
+	return lnk.warn(strict, SectionExtraData, offset, "ExtraData ends without a TerminalBlock", io.ErrUnexpectedEOF)
+}
+
+// truncatedBlock handles ExtraData that ends within a block, which is then
+// ignored.
+func (lnk *LNK) truncatedBlock(strict bool, offset int64) error {
+	return lnk.warn(strict, SectionExtraData, offset, "ExtraData block is truncated", io.ErrUnexpectedEOF)
+}
+
+// readEnvironmentData decodes the fixed-size ANSI and Unicode paths shared by
+// the EnvironmentVariableDataBlock and the IconEnvironmentDataBlock.
+func readEnvironmentData(block []byte) (*EnvironmentVariableData, error) {
+	if len(block) != 0x314-8 {
+		return nil, ErrInvalidSize
+	}
+	return &EnvironmentVariableData{
+		TargetANSI:    cString(block[:260]),
+		TargetUnicode: decodeUTF16(block[260:]),
+	}, nil
+}
+
+func (env *EnvironmentVariableData) bytes() []byte {
+	block := make([]byte, 0x314-8)
+	ansi := env.TargetANSI
+	if len(ansi) > 259 {
+		ansi = ansi[:259]
+	}
+	copy(block, ansi)
+	for i, char := range encodeUTF16(env.TargetUnicode, 259) {
+		endianness.PutUint16(block[260+2*i:], char)
+	}
+	return block
+}
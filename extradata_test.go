@@ -0,0 +1,141 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseTruncatedExtraData(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{RetainRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := len(data) - len(lnk.RawExtraData)
+
+	// every cut before the TerminalBlock, from a lone BlockSize to a block
+	// missing its last byte
+	if start == len(data) {
+		t.Fatal("no ExtraData")
+	}
+	for end := start; end < len(data)-4; end++ {
+		for _, opts := range []ParseOptions{{}, {Lazy: true}} {
+			lnk, err := ParseWithOptions(bytes.NewReader(data[:end]), opts)
+			if err != nil {
+				t.Fatalf("cut at %d, lazy %v: %v", end, opts.Lazy, err)
+			}
+			if len(lnk.Warnings()) == 0 {
+				t.Errorf("cut at %d, lazy %v: no warnings", end, opts.Lazy)
+			}
+			if err := lnk.LoadExtraData(); err != nil {
+				t.Errorf("cut at %d, lazy %v: LoadExtraData: %v", end, opts.Lazy, err)
+			}
+		}
+
+		_, err := ParseWithOptions(bytes.NewReader(data[:end]), ParseOptions{Strict: true})
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("cut at %d, strict: error = %v, want a ParseError", end, err)
+		}
+		if err == io.EOF {
+			t.Errorf("cut at %d, strict: io.EOF", end)
+		}
+	}
+}
+
+func TestParseMissingTerminalBlock(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	data = data[:len(data)-4]
+
+	lnk, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lnk.Warnings()) != 1 {
+		t.Errorf("Warnings() = %q, want one warning", lnk.Warnings())
+	}
+	// the blocks before the TerminalBlock are kept
+	if lnk.KnownFolder == nil || lnk.EnvironmentVariable == nil {
+		t.Errorf("KnownFolder = %v, EnvironmentVariable = %v", lnk.KnownFolder, lnk.EnvironmentVariable)
+	}
+
+	_, err = ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Section != SectionExtraData {
+		t.Errorf("strict: error = %v, want a ParseError in the ExtraData", err)
+	}
+}
+
+func TestParseLazy(t *testing.T) {
+	for _, name := range []string{"tracker.lnk", "local.lnk", "console.lnk"} {
+		data := readTestdata(t, name)
+		eager := load(t, name)
+		lazy, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{Lazy: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if lazy.Tracker != nil || lazy.Console != nil || lazy.EnvironmentVariable != nil {
+			t.Errorf("%s: ExtraData decoded eagerly", name)
+		}
+
+		tracker, err := lazy.TrackerDataBlock()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(tracker, eager.Tracker) {
+			t.Errorf("%s: TrackerDataBlock() = %+v, want %+v", name, tracker, eager.Tracker)
+		}
+		if !reflect.DeepEqual(lazy.Console, eager.Console) || !reflect.DeepEqual(lazy.EnvironmentVariable, eager.EnvironmentVariable) {
+			t.Errorf("%s: LoadExtraData() decoded different blocks", name)
+		}
+
+		got, err := lazy.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := eager.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: MarshalBinary() differs after parsing lazily", name)
+		}
+	}
+}
+
+func BenchmarkParseCorpusLazy(b *testing.B) {
+	benchmarkParseCorpus(b, ParseOptions{Lazy: true})
+}
+
+func TestRegisterBlockDecoder(t *testing.T) {
+	const signature = 0x12345678
+	RegisterBlockDecoder(signature, func(data []byte) (interface{}, error) {
+		return string(data), nil
+	})
+	defer RegisterBlockDecoder(signature, nil)
+
+	lnk := load(t, "vendor.lnk")
+	if got := lnk.DecodedBlocks[signature]; got != "abcd" {
+		t.Errorf("DecodedBlocks[0x%x] = %v, want %q", signature, got, "abcd")
+	}
+	// the block is still written
+	if len(lnk.UnknownBlocks) == 0 || lnk.UnknownBlocks[0].Signature != signature {
+		t.Errorf("UnknownBlocks = %v", lnk.UnknownBlocks)
+	}
+
+	errDecode := errors.New("bad block")
+	RegisterBlockDecoder(signature, func([]byte) (interface{}, error) {
+		return nil, errDecode
+	})
+	if _, err := Parse(bytes.NewReader(readTestdata(t, "vendor.lnk"))); !errors.Is(err, errDecode) {
+		t.Errorf("Parse() error = %v, want %v", err, errDecode)
+	}
+
+	RegisterBlockDecoder(signature, nil)
+	if lnk := load(t, "vendor.lnk"); lnk.DecodedBlocks != nil {
+		t.Errorf("DecodedBlocks = %v after unregistering", lnk.DecodedBlocks)
+	}
+}
@@ -0,0 +1,23 @@
+package lnk
+
+import "io/fs"
+
+// TargetFileMode approximates the FileAttributes of the target as an
+// fs.FileMode, the way os.Stat does on Windows: read-only targets have the
+// permissions 0444 rather than 0666, and directories have ModeDir and the
+// execute bits. Targets known to be symbolic links (see ReparseKind) have
+// ModeSymlink. Other attributes, such as Hidden and System, have no
+// equivalent.
+func (lnk *LNK) TargetFileMode() fs.FileMode {
+	var mode fs.FileMode = 0666
+	if lnk.ReadOnly {
+		mode = 0444
+	}
+	if lnk.Directory {
+		mode |= fs.ModeDir | 0111
+	}
+	if lnk.ReparseKind() == "symlink" {
+		mode |= fs.ModeSymlink
+	}
+	return mode
+}
@@ -0,0 +1,42 @@
+package lnk
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+)
+
+func TestTargetFileMode(t *testing.T) {
+	dir := NewBuilder()
+	if err := dir.SetTargetPath(`D:\Data\`); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\Windows\win.ini`); err != nil {
+		t.Fatal(err)
+	}
+	readOnly := b.Build()
+	readOnly.ReadOnly = true
+	data, err := readOnly.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readOnly, err = Parse(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		lnk  *LNK
+		want fs.FileMode
+	}{
+		{"directory", roundTrip(t, dir), fs.ModeDir | 0777},
+		{"read-only file", readOnly, 0444},
+		{"file", load(t, "local.lnk"), 0666},
+	}
+	for _, test := range tests {
+		if got := test.lnk.TargetFileMode(); got != test.want {
+			t.Errorf("%s: TargetFileMode() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package lnk
+
+// WriterSignature describes the LinkFlags and ExtraData blocks that a
+// shortcut creator characteristically writes.
+type WriterSignature struct {
+	// Writer is the name returned by WriterFingerprint.
+	Writer string
+	// The LinkFlags masked with FlagMask must equal Flags.
+	FlagMask uint32
+	Flags    uint32
+	// Blocks are the signatures of the ExtraData blocks that must be present,
+	// and Absent those of the blocks that must not be.
+	Blocks []uint32
+	Absent []uint32
+}
+
+// LinkFlags bits used by WriterSignatures
+const (
+	flagHasLinkTargetIDList  = 1 << 0
+	flagHasLinkInfo          = 1 << 1
+	flagIsUnicode            = 1 << 7
+	flagHasDarwinID          = 1 << 12
+	flagEnableTargetMetadata = 1 << 19
+)
+
+// WriterSignatures are the signatures WriterFingerprint matches, in order of
+// precedence. It may be modified to tune the heuristic, but not concurrently
+// with calls to WriterFingerprint.
+var WriterSignatures = []WriterSignature{
+	{
+		// advertised shortcuts are created by msiexec whatever the shell
+		Writer:   "Windows Installer",
+		FlagMask: flagHasDarwinID,
+		Flags:    flagHasDarwinID,
+		Blocks:   []uint32{DarwinDataBlockSignature},
+	},
+	{
+		// Explorer records the metadata of the target in a property store
+		// since Windows 7, along with the tracker data of the target
+		Writer:   "Explorer",
+		FlagMask: flagHasLinkTargetIDList | flagHasLinkInfo | flagIsUnicode | flagEnableTargetMetadata,
+		Flags:    flagHasLinkTargetIDList | flagHasLinkInfo | flagIsUnicode | flagEnableTargetMetadata,
+		Blocks:   []uint32{TrackerDataBlockSignature, PropertyStoreDataBlockSignature},
+	},
+	{
+		// programs that save through IShellLink, such as the Windows Script
+		// Host, get the tracker data but not the target metadata
+		Writer:   "IShellLink",
+		FlagMask: flagHasLinkTargetIDList | flagHasLinkInfo | flagIsUnicode | flagEnableTargetMetadata,
+		Flags:    flagHasLinkTargetIDList | flagHasLinkInfo | flagIsUnicode,
+		Blocks:   []uint32{TrackerDataBlockSignature},
+	},
+	{
+		// libraries that write the format themselves rarely synthesize an
+		// IDList or tracker data
+		Writer:   "third-party library",
+		FlagMask: flagHasLinkTargetIDList,
+		Flags:    0,
+		Absent:   []uint32{TrackerDataBlockSignature},
+	},
+}
+
+// WriterFingerprint classifies the program that likely created the shortcut,
+// such as "Explorer", by the first of WriterSignatures it matches. It returns
+// "unknown" if none match. It is a heuristic for attribution, as any writer
+// can imitate another, and the flags may have been changed since.
+func (lnk *LNK) WriterFingerprint() string {
+	lnk.LoadExtraData()
+	blocks := make(map[uint32]bool)
+	for _, signature := range lnk.extraBlocks {
+		blocks[signature] = true
+	}
+
+signatures:
+	for _, signature := range WriterSignatures {
+		if lnk.linkFlags()&signature.FlagMask != signature.Flags {
+			continue
+		}
+		for _, block := range signature.Blocks {
+			if !blocks[block] {
+				continue signatures
+			}
+		}
+		for _, block := range signature.Absent {
+			if blocks[block] {
+				continue signatures
+			}
+		}
+		return signature.Writer
+	}
+	return "unknown"
+}
@@ -0,0 +1,44 @@
+package lnk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriterFingerprint(t *testing.T) {
+	// a shortcut as Explorer writes it
+	lnk := load(t, "local.lnk")
+	lnk.EnableTargetMetadata = true
+	lnk.Tracker = load(t, "tracker.lnk").Tracker
+	lnk.PropertyStore = load(t, "uwp.lnk").PropertyStore
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	explorer, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := explorer.WriterFingerprint(); got != "Explorer" {
+		t.Errorf("WriterFingerprint() = %q, want %q", got, "Explorer")
+	}
+
+	// without the property store of the target metadata
+	explorer.EnableTargetMetadata = false
+	if got := explorer.WriterFingerprint(); got != "IShellLink" {
+		t.Errorf("WriterFingerprint() = %q, want %q", got, "IShellLink")
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"local.lnk", "unknown"},
+		{"unc.lnk", "third-party library"},
+	}
+	for _, test := range tests {
+		if got := load(t, test.file).WriterFingerprint(); got != test.want {
+			t.Errorf("%s: WriterFingerprint() = %q, want %q", test.file, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenFiles are the shortcuts in testdata whose parsed fields are recorded
+// in golden files.
+var goldenFiles = []string{
+	"local.lnk",
+	"unc.lnk",
+	"knownfolder.lnk",
+	"console.lnk",
+	"uwp.lnk",
+}
+
+func TestGolden(t *testing.T) {
+	for _, name := range goldenFiles {
+		t.Run(name, func(t *testing.T) {
+			got, err := json.MarshalIndent(load(t, name), "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "testdata/"+strings.TrimSuffix(name, ".lnk")+".golden", append(got, '\n'))
+		})
+	}
+}
+
+// checkGolden compares got with the contents of the golden file, which it
+// replaces with got first if -update is set.
+func checkGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%s", golden, got)
+	}
+}
+
+func TestGoldenRoundTrip(t *testing.T) {
+	for _, name := range goldenFiles {
+		lnk := load(t, name)
+		want, err := json.Marshal(lnk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if _, err := lnk.WriteTo(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		written, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := json.Marshal(written)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: fields changed when written:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+}
+
+func TestGoldenTargets(t *testing.T) {
+	tests := []struct {
+		file        string
+		target      string
+		knownFolder string
+		aumid       string
+	}{
+		{"local.lnk", `C:\test\a.txt`, `C:\test\a.txt`, ""},
+		{"unc.lnk", `\\server\share\file.txt`, "", ""},
+		{"knownfolder.lnk", `C:\Users\Public\Downloads\report.pdf`, "report.pdf", ""},
+		{"console.lnk", `C:\Windows\System32\cmd.exe`, "", ""},
+		{"uwp.lnk", "", "", "Microsoft.WindowsCalculator_8wekyb3d8bbwe!App"},
+	}
+	for _, test := range tests {
+		lnk := load(t, test.file)
+		if target, _ := lnk.ResolveTarget(); target != test.target {
+			t.Errorf("%s: ResolveTarget() = %q, want %q", test.file, target, test.target)
+		}
+		if child, _ := lnk.KnownFolderChildPath(); child != test.knownFolder {
+			t.Errorf("%s: KnownFolderChildPath() = %q, want %q", test.file, child, test.knownFolder)
+		}
+		if aumid, _ := lnk.AppUserModelID(); aumid != test.aumid {
+			t.Errorf("%s: AppUserModelID() = %q, want %q", test.file, aumid, test.aumid)
+		}
+	}
+}
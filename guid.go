@@ -0,0 +1,68 @@
+package lnk
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidGUID is returned by ParseGUID when a string is not a GUID.
+var ErrInvalidGUID = errors.New("invalid GUID")
+
+// GUID is a GUID in its on-disk byte order, where the first three groups are
+// little-endian, as in CLSIDs, KNOWNFOLDERIDs, droids and format IDs.
+type GUID [16]byte
+
+// String returns the registry form of the GUID, e.g.
+// "{00021401-0000-0000-C000-000000000046}".
+func (id GUID) String() string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		endianness.Uint32(id[0:]), endianness.Uint16(id[4:]), endianness.Uint16(id[6:]),
+		id[8:10], id[10:])
+}
+
+// ParseGUID is the inverse of String. The braces are optional, and case is
+// ignored.
+func ParseGUID(str string) (GUID, error) {
+	var id GUID
+	if strings.HasPrefix(str, "{") && strings.HasSuffix(str, "}") {
+		str = str[1 : len(str)-1]
+	}
+	if len(str) != 36 || str[8] != '-' || str[13] != '-' || str[18] != '-' || str[23] != '-' {
+		return id, ErrInvalidGUID
+	}
+	raw, err := hex.DecodeString(strings.ReplaceAll(str, "-", ""))
+	if err != nil || len(raw) != 16 {
+		return id, ErrInvalidGUID
+	}
+
+	id[0], id[1], id[2], id[3] = raw[3], raw[2], raw[1], raw[0]
+	id[4], id[5] = raw[5], raw[4]
+	id[6], id[7] = raw[7], raw[6]
+	copy(id[8:], raw[8:])
+	return id, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, so that GUIDs are encoded in
+// their registry form, as in JSON.
+func (id GUID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseGUID.
+func (id *GUID) UnmarshalText(text []byte) error {
+	var err error
+	*id, err = ParseGUID(string(text))
+	return err
+}
+
+// guid is ParseGUID, but it panics if str is malformed, so it should only be
+// used with constants.
+func guid(str string) GUID {
+	id, err := ParseGUID(str)
+	if err != nil {
+		panic("lnk: invalid GUID " + str)
+	}
+	return id
+}
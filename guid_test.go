@@ -0,0 +1,44 @@
+package lnk
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseGUID(t *testing.T) {
+	const str = "{00021401-0000-0000-C000-000000000046}"
+	id, err := ParseGUID(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != validCLSID {
+		t.Errorf("ParseGUID(%q) = %x, want %x", str, id, validCLSID)
+	}
+	if id.String() != str {
+		t.Errorf("String() = %q, want %q", id.String(), str)
+	}
+	if lower, err := ParseGUID("00021401-0000-0000-c000-000000000046"); err != nil || lower != id {
+		t.Errorf("ParseGUID without braces = %v, %v", lower, err)
+	}
+
+	for _, invalid := range []string{"", "{}", "00021401-0000-0000-C000-00000000004", "00021401x0000-0000-C000-000000000046", "0002140g-0000-0000-C000-000000000046"} {
+		if _, err := ParseGUID(invalid); err != ErrInvalidGUID {
+			t.Errorf("ParseGUID(%q) error = %v, want %v", invalid, err, ErrInvalidGUID)
+		}
+	}
+}
+
+func TestGUIDJSON(t *testing.T) {
+	data, err := json.Marshal(validCLSID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"{00021401-0000-0000-C000-000000000046}"` {
+		t.Errorf("json.Marshal = %s", data)
+	}
+
+	var id GUID
+	if err := json.Unmarshal(data, &id); err != nil || id != validCLSID {
+		t.Errorf("json.Unmarshal = %v, %v", id, err)
+	}
+}
@@ -0,0 +1,46 @@
+package lnk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+)
+
+// LinkFlags covered by SemanticHash: RunInSeperateProcess, RunAsUser,
+// RunWithShimLayer and AllowLinkToLink
+const semanticLinkFlags = 1<<10 | 1<<13 | 1<<17 | 1<<23
+
+// SemanticHash returns the hex-encoded SHA-256 hash of what the shortcut
+// launches, so that shortcuts that were created at different times or on
+// different machines but launch the same thing hash identically. The hash
+// covers, in order:
+//
+//   - the path returned by ResolveTarget, or an empty string if it fails
+//   - Arguments
+//   - WorkingDir
+//   - the icon path and index returned by Icon
+//   - ShowCommand
+//   - the LinkFlags that change how the target is launched, which are
+//     RunInSeperateProcess, RunAsUser, RunWithShimLayer and AllowLinkToLink
+//
+// each string being prefixed by its length in bytes. Timestamps, FileSize,
+// the VolumeID, the TrackerDataBlock and the other ExtraData blocks are
+// excluded, as are the LinkFlags that only describe how the shortcut is
+// stored, such as IsUnicode, HasLinkInfo, HasLinkTargetIDList, HasName and
+// EnableTargetMetadata.
+func (lnk *LNK) SemanticHash() string {
+	target, _ := lnk.ResolveTarget()
+	icon, iconIndex, _ := lnk.Icon()
+
+	var data []byte
+	for _, str := range []string{target, lnk.Arguments, lnk.WorkingDir, icon, strconv.Itoa(iconIndex)} {
+		data = binary.AppendUvarint(data, uint64(len(str)))
+		data = append(data, str...)
+	}
+	data = endianness.AppendUint32(data, lnk.ShowCommand)
+	data = endianness.AppendUint32(data, lnk.linkFlags()&semanticLinkFlags)
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,49 @@
+package lnk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSemanticHash(t *testing.T) {
+	want := load(t, "local.lnk").SemanticHash()
+
+	same := []struct {
+		name   string
+		modify func(lnk *LNK)
+	}{
+		{"timestamps", func(lnk *LNK) {
+			lnk.CreationTime = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+			lnk.AccessTime = lnk.CreationTime
+			lnk.WriteTime = lnk.CreationTime
+		}},
+		{"FileSize", func(lnk *LNK) { lnk.FileSize++ }},
+		{"IsUnicode", func(lnk *LNK) { lnk.IsUnicode = !lnk.IsUnicode }},
+		{"HasName", func(lnk *LNK) { lnk.HasName = !lnk.HasName }},
+		{"EnableTargetMetadata", func(lnk *LNK) { lnk.EnableTargetMetadata = !lnk.EnableTargetMetadata }},
+	}
+	for _, test := range same {
+		lnk := load(t, "local.lnk")
+		test.modify(lnk)
+		if got := lnk.SemanticHash(); got != want {
+			t.Errorf("%s: hash changed", test.name)
+		}
+	}
+
+	different := []struct {
+		name   string
+		modify func(lnk *LNK)
+	}{
+		{"Arguments", func(lnk *LNK) { lnk.Arguments = "/s" }},
+		{"ShowCommand", func(lnk *LNK) { lnk.ShowCommand = ShowMinNoActive }},
+		{"RunAsUser", func(lnk *LNK) { lnk.RunAsUser = !lnk.RunAsUser }},
+		{"RunInSeperateProcess", func(lnk *LNK) { lnk.RunInSeperateProcess = !lnk.RunInSeperateProcess }},
+	}
+	for _, test := range different {
+		lnk := load(t, "local.lnk")
+		test.modify(lnk)
+		if got := lnk.SemanticHash(); got == want {
+			t.Errorf("%s: hash did not change", test.name)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNoKnownFolder is returned when a shortcut has no
+	// KnownFolderDataBlock.
+	ErrNoKnownFolder = errors.New("no known folder")
+
+	// ErrInvalidOffset is returned when an offset into the IDList does not
+	// land on an ItemID boundary.
+	ErrInvalidOffset = errors.New("offset is not on an ItemID boundary")
+
+	// ErrUnknownItemID is returned when the name of an ItemID cannot be
+	// decoded.
+	ErrUnknownItemID = errors.New("unknown ItemID")
+
+	// ErrTruncatedIDList is returned along with the ItemIDs that could be
+	// read when splitting an IDList leniently.
+	ErrTruncatedIDList = errors.New("truncated IDList")
+)
+
+// signature of the file entry extension block holding the long name
+var beef0004 = []byte{0x04, 0x00, 0xef, 0xbe}
+
+// IDListOptions controls how the IDList is split into ItemIDs.
+type IDListOptions struct {
+	// Lenient returns the ItemIDs that precede an ItemID that overruns the
+	// IDList, or a missing TerminalID, along with ErrTruncatedIDList, rather
+	// than failing with ErrInvalidSize.
+	Lenient bool
+}
+
+// ItemIDs splits IDListBytes into its ItemIDs. Each returned ItemID excludes
+// its ItemIDSize field, and the TerminalID is not included
+// (MS-SHLLINK 2.2.2).
+func (lnk *LNK) ItemIDs() ([][]byte, error) {
+	return lnk.ItemIDsWithOptions(IDListOptions{})
+}
+
+// ItemIDsWithOptions is like ItemIDs, using opts.
+func (lnk *LNK) ItemIDsWithOptions(opts IDListOptions) ([][]byte, error) {
+	return splitIDList(lnk.IDListBytes, opts)
+}
+
+// IDListDepth returns the number of ItemIDs in the LinkTargetIDList, excluding
+// the TerminalID, which grows with the depth of the target in the namespace:
+// a file in `C:\Users\Public` has 5, for My Computer, the drive, the two
+// folders and the file itself. It returns 0 if there is no IDList. A
+// malformed list is counted up to the ItemID that is malformed.
+func (lnk *LNK) IDListDepth() int {
+	items, _ := lnk.ItemIDsWithOptions(IDListOptions{Lenient: true})
+	return len(items)
+}
+
+// splitIDList implements ItemIDsWithOptions for an IDList. A shortcut without
+// an IDList has no ItemIDs, rather than an IDList missing its TerminalID.
+func splitIDList(data []byte, opts IDListOptions) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var items [][]byte
+	for len(data) >= 2 {
+		size := int(endianness.Uint16(data))
+		// TerminalID
+		if size == 0 {
+			return items, nil
+		}
+		if size < 2 || size > len(data) {
+			break
+		}
+		items = append(items, data[2:size])
+		data = data[size:]
+	}
+
+	if opts.Lenient {
+		return items, ErrTruncatedIDList
+	}
+	return nil, ErrInvalidSize
+}
+
+// KnownFolderChildPath returns the path of the target relative to the known
+// folder in the KnownFolderDataBlock, such as "report.pdf" for a shortcut to
+// a file in Downloads. Unlike an absolute path, it does not change when the
+// known folder is relocated.
+func (lnk *LNK) KnownFolderChildPath() (string, error) {
+	lnk.LoadExtraData()
+	if lnk.KnownFolder == nil {
+		return "", ErrNoKnownFolder
+	}
+
+	items, err := lnk.ItemIDs()
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	offset := 0
+	for _, item := range items {
+		if offset == int(lnk.KnownFolder.Offset) || names != nil {
+			name, ok := lnk.itemName(item)
+			if !ok {
+				return "", ErrUnknownItemID
+			}
+			names = append(names, name)
+		}
+		offset += 2 + len(item)
+	}
+	// the offset may point at the TerminalID if the target is the folder
+	if names == nil && offset != int(lnk.KnownFolder.Offset) {
+		return "", ErrInvalidOffset
+	}
+
+	return strings.Join(names, `\`), nil
+}
+
+// IDListPath returns the path of the target from the names of its ItemIDs,
+// such as `C:\Windows\notepad.exe`. Root folder items, such as My Computer,
+// are skipped. If the path cannot be decoded from the LinkTargetIDList, it is
+// decoded from the VistaAndAboveIDListDataBlock, if there is one; see
+// IDListPathSource.
+func (lnk *LNK) IDListPath() (string, error) {
+	path, _, err := lnk.IDListPathSource()
+	return path, err
+}
+
+// IDListPathSource is like IDListPath, but also reports whether the path was
+// decoded from the VistaAndAboveIDListDataBlock rather than from the
+// LinkTargetIDList. Modern shortcuts may only have a stub in the latter.
+func (lnk *LNK) IDListPathSource() (path string, vista bool, err error) {
+	path, err = lnk.idListPath(lnk.IDListBytes)
+	if err == nil && path != "" {
+		return path, false, nil
+	}
+
+	lnk.LoadExtraData()
+	if len(lnk.VistaAndAboveIDList) != 0 {
+		vistaPath, vistaErr := lnk.idListPath(lnk.VistaAndAboveIDList)
+		if vistaErr == nil && vistaPath != "" {
+			return vistaPath, true, nil
+		}
+	}
+	return path, false, err
+}
+
+// idListPath implements IDListPath for an IDList.
+func (lnk *LNK) idListPath(data []byte) (string, error) {
+	items, err := splitIDList(data, IDListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, item := range items {
+		// root folder
+		if len(item) != 0 && item[0]&0x70 == 0x10 {
+			continue
+		}
+		name, ok := lnk.itemName(item)
+		if !ok {
+			return "", ErrUnknownItemID
+		}
+		names = append(names, name)
+	}
+
+	path := strings.Join(names, `\`)
+	// a volume on its own needs its trailing backslash
+	if len(names) == 1 && strings.HasSuffix(path, ":") {
+		path += `\`
+	}
+	return path, nil
+}
+
+// itemName returns the name of a volume, file entry or network location shell
+// item. For file entries, the Unicode long name in the extension block is
+// preferred over the primary (8.3) name, which is in the system code page
+// unless it is flagged as Unicode.
+func (lnk *LNK) itemName(item []byte) (string, bool) {
+	if len(item) < 2 {
+		return "", false
+	}
+
+	switch item[0] & 0x70 {
+	// volume
+	case 0x20:
+		return strings.TrimRight(cString(item[1:]), `\`), true
+	// file entry
+	case 0x30:
+		if len(item) < 12 {
+			return "", false
+		}
+		if name, ok := longName(item); ok {
+			return name, true
+		}
+		if item[0]&0x04 != 0 {
+			return decodeUTF16(item[12:]), true
+		}
+		return lnk.ansiString(item[12:]), true
+	// network location
+	case 0x40:
+		if len(item) < 4 {
+			return "", false
+		}
+		return lnk.ansiString(item[4:]), true
+	}
+	return "", false
+}
+
+// longName returns the long name from the 0xBEEF0004 extension block of a file
+// entry shell item.
+func longName(item []byte) (string, bool) {
+	i := bytes.Index(item[12:], beef0004)
+	if i < 4 {
+		return "", false
+	}
+	ext := item[12+i-4:]
+	size := int(endianness.Uint16(ext))
+	// the size covers itself, the version and the signature
+	if size < 8 || size > len(ext) {
+		return "", false
+	}
+	ext = ext[:size]
+
+	version := endianness.Uint16(ext[2:])
+	// creation time, access time and version identifier
+	offset := 18
+	if version >= 7 {
+		// reserved, file reference and reserved
+		offset += 18
+	}
+	if version >= 3 {
+		// long string size
+		offset += 2
+	}
+	if version >= 9 {
+		offset += 4
+	}
+	if version >= 8 {
+		offset += 4
+	}
+	if offset >= len(ext) {
+		return "", false
+	}
+
+	name := decodeUTF16(ext[offset:])
+	return name, name != ""
+}
+
+// ansiString returns the bytes up to the first NUL as a string, decoded with
+// ParseOptions.DecodeANSI if it was set.
+func (lnk *LNK) ansiString(data []byte) string {
+	if lnk.decodeANSI == nil {
+		return cString(data)
+	}
+	if i := bytes.IndexByte(data, 0); i != -1 {
+		data = data[:i]
+	}
+	return lnk.decodeANSI(data)
+}
+
+// cString returns the bytes up to the first NUL as a string.
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i != -1 {
+		data = data[:i]
+	}
+	return string(data)
+}
+
+// hasTrailingData reports whether anything but NULs follows the first NUL
+// character of data, whose characters are width bytes wide. The strings of a
+// shortcut end at their first NUL, so such data is hidden from readers.
+func hasTrailingData(data []byte, width int) bool {
+	for i := 0; i+width <= len(data); i += width {
+		if bytes.Count(data[i:i+width], []byte{0}) == width {
+			return bytes.Count(data[i+width:], []byte{0}) != len(data[i+width:])
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestItemNameShort(t *testing.T) {
+	lnk := &LNK{}
+	for _, item := range [][]byte{
+		{},
+		{0x41},
+		{0x41, 0x00},
+		{0x41, 0x00, 0x00},
+		{0x31, 0x00, 0x00, 0x00},
+	} {
+		if name, ok := lnk.itemName(item); ok {
+			t.Errorf("itemName(%x) = %q, want failure", item, name)
+		}
+	}
+
+	if name, ok := lnk.itemName([]byte{0x41, 0x00, 0x00, 0x00, 's', 'r', 'v', 0}); !ok || name != "srv" {
+		t.Errorf("itemName = %q, %v, want srv", name, ok)
+	}
+}
+
+func TestLongNameShortExtension(t *testing.T) {
+	for size := 0; size < 8; size++ {
+		item := make([]byte, 12)
+		item[0] = 0x32
+		copy(item[2:], "ABCDEFGHI")
+		item = append(item, byte(size), 0x00, 0x09, 0x00, 0x04, 0x00, 0xef, 0xbe)
+		if name, ok := longName(item); ok {
+			t.Errorf("size %d: longName = %q, want failure", size, name)
+		}
+	}
+}
+
+func TestIDListPathMalformedItems(t *testing.T) {
+	lnk := &LNK{IDListBytes: []byte{
+		0x03, 0x00, 0x41,
+		0x00, 0x00,
+	}}
+	if _, err := lnk.IDListPath(); err != ErrUnknownItemID {
+		t.Errorf("IDListPath() error = %v, want %v", err, ErrUnknownItemID)
+	}
+}
+
+func TestIDListPathLongNames(t *testing.T) {
+	// the short names are DOWNLO~1 and REPORT~1.PDF
+	lnk := load(t, "knownfolder.lnk")
+	const want = `C:\Users\Public\Downloads\report.pdf`
+	if path, err := lnk.IDListPath(); err != nil || path != want {
+		t.Errorf("IDListPath() = %q, %v, want %q", path, err, want)
+	}
+}
+
+func TestIDListPathShortNameCodePage(t *testing.T) {
+	// a file entry without an extension block, whose short name is in
+	// Windows-1252
+	item := []byte{0x32, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x20, 0, 'C', 'A', 'F', 0xc9, '~', '1', 0}
+	idList := append([]byte{byte(len(item) + 2), 0}, item...)
+	idList = append(idList, 0, 0)
+
+	b := NewBuilder()
+	lnk := b.Build()
+	lnk.IDListBytes = idList
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latin1 := func(data []byte) string {
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	lnk, err = ParseWithOptions(bytes.NewReader(data), ParseOptions{DecodeANSI: latin1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path, err := lnk.IDListPath(); err != nil || path != "CAFÉ~1" {
+		t.Errorf("IDListPath() = %q, %v, want CAFÉ~1", path, err)
+	}
+}
+
+func TestItemIDsTruncated(t *testing.T) {
+	lnk := load(t, "local.lnk")
+	full, err := lnk.ItemIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cut within the last ItemID
+	lnk.IDListBytes = lnk.IDListBytes[:len(lnk.IDListBytes)-10]
+	if items, err := lnk.ItemIDs(); items != nil || err != ErrInvalidSize {
+		t.Errorf("ItemIDs() = %d items, %v, want %v", len(items), err, ErrInvalidSize)
+	}
+	items, err := lnk.ItemIDsWithOptions(IDListOptions{Lenient: true})
+	if err != ErrTruncatedIDList {
+		t.Errorf("lenient error = %v, want %v", err, ErrTruncatedIDList)
+	}
+	if len(items) != len(full)-1 {
+		t.Fatalf("lenient: %d items, want %d", len(items), len(full)-1)
+	}
+	for i, item := range items {
+		if !bytes.Equal(item, full[i]) {
+			t.Errorf("item %d = %x, want %x", i, item, full[i])
+		}
+	}
+}
+
+func TestIDListPathVistaFallback(t *testing.T) {
+	lnk := load(t, "local.lnk")
+	const want = `C:\test\a.txt`
+	if path, vista, err := lnk.IDListPathSource(); path != want || vista || err != nil {
+		t.Errorf("IDListPathSource() = %q, %v, %v, want %q from the LinkTargetIDList", path, vista, err, want)
+	}
+
+	// the LinkTargetIDList is a stub, or cannot be decoded
+	stubs := [][]byte{
+		{0x00, 0x00},
+		{0x05, 0x00, 0x70, 0x01, 0x02, 0x00, 0x00},
+	}
+	for _, stub := range stubs {
+		lnk := load(t, "local.lnk")
+		lnk.VistaAndAboveIDList = lnk.IDListBytes
+		lnk.IDListBytes = stub
+		data, err := lnk.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lnk, err = Parse(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if path, vista, err := lnk.IDListPathSource(); path != want || !vista || err != nil {
+			t.Errorf("IDListPathSource() with % x = %q, %v, %v, want %q from the VistaAndAboveIDListDataBlock", stub, path, vista, err, want)
+		}
+	}
+}
+
+func TestParseMissingTerminalID(t *testing.T) {
+	lnk := NewBuilder().Build()
+	// My Computer, with no TerminalID after it
+	lnk.IDListBytes = []byte{0x14, 0x00, 0x1f, 0x50, 0xe0, 0x4f, 0xd0, 0x20, 0xea, 0x3a, 0x69, 0x10, 0xa2, 0xd8, 0x08, 0x00, 0x2b, 0x30, 0x30, 0x9d}
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lnk, err = Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lnk.Warnings()) != 1 {
+		t.Errorf("Warnings() = %q, want one warning", lnk.Warnings())
+	}
+
+	_, err = ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	var parseErr *ParseError
+	// the offset of the last two bytes of the IDList
+	if !errors.Is(err, ErrNoTerminalID) || !errors.As(err, &parseErr) || parseErr.Offset != 76+2+18 {
+		t.Errorf("strict: error = %v, want %v at offset %d", err, ErrNoTerminalID, 76+2+18)
+	}
+
+	if _, err := ParseWithOptions(bytes.NewReader(readTestdata(t, "local.lnk")), ParseOptions{Strict: true}); err != nil {
+		t.Errorf("strict: %v with a TerminalID", err)
+	}
+}
+
+func TestIDListDepth(t *testing.T) {
+	tests := []struct {
+		file string
+		want int
+	}{
+		// My Computer, C:, Users, Public, Downloads and report.pdf
+		{"knownfolder.lnk", 6},
+		{"local.lnk", 4},
+		{"uwp.lnk", 1},
+		{"unc.lnk", 0},
+	}
+	for _, test := range tests {
+		if got := load(t, test.file).IDListDepth(); got != test.want {
+			t.Errorf("%s: IDListDepth() = %d, want %d", test.file, got, test.want)
+		}
+	}
+
+	// a malformed list is counted up to the malformed ItemID
+	lnk := load(t, "knownfolder.lnk")
+	lnk.IDListBytes = lnk.IDListBytes[:len(lnk.IDListBytes)-10]
+	if got := lnk.IDListDepth(); got != 5 {
+		t.Errorf("IDListDepth() = %d for a truncated list, want 5", got)
+	}
+}
+
+func TestNoIDList(t *testing.T) {
+	lnk := load(t, "unc.lnk")
+	if items, err := lnk.ItemIDs(); items != nil || err != nil {
+		t.Errorf("ItemIDs() = %d items, %v, want none", len(items), err)
+	}
+	if got := lnk.IDListDepth(); got != 0 {
+		t.Errorf("IDListDepth() = %d, want 0", got)
+	}
+	if path, err := lnk.IDListPath(); path != "" || err != nil {
+		t.Errorf("IDListPath() = %q, %v, want no path", path, err)
+	}
+
+	// the known folder is then the target itself
+	lnk.KnownFolder = &KnownFolderData{}
+	if path, err := lnk.KnownFolderChildPath(); path != "" || err != nil {
+		t.Errorf("KnownFolderChildPath() = %q, %v, want no path", path, err)
+	}
+}
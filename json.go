@@ -0,0 +1,86 @@
+package lnk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JSONSchema identifies the layout of the JSON produced by MarshalJSON. It
+// changes whenever fields are added, removed or reinterpreted, so that
+// consumers can detect it:
+//   - lnk/v1 is the initial layout.
+//   - lnk/v2 has timestamps in UTC, or in ParseOptions.Location, rather than
+//     in the local time zone, and adds fields such as LinkFlags,
+//     FileAttributes, ValidDevice, ValidNetType, DeviceName and
+//     CommonPathSuffixUnicode.
+//   - lnk/v3 encodes GUIDs, such as CLSID and the tracker droids, as strings
+//     in registry form rather than as arrays of their 16 bytes, and adds
+//     EndOffset.
+const JSONSchema = "lnk/v3"
+
+// jsonParser notes the specification and protocol revision the fields are
+// decoded according to, which is the one LNK conforms to.
+const jsonParser = "MS-SHLLINK 3.0"
+
+// MarshalJSON encodes the fields of the shortcut as a JSON object, along with
+// a "_schema" field holding JSONSchema and a "parser" field naming the
+// specification and protocol revision the shortcut was decoded according to.
+// It implements json.Marshaler.
+func (lnk *LNK) MarshalJSON() ([]byte, error) {
+	err := lnk.LoadExtraData()
+	if err != nil {
+		return nil, err
+	}
+
+	// a distinct type, so that its own MarshalJSON method is not called
+	type fields LNK
+	return json.Marshal(struct {
+		Schema string `json:"_schema"`
+		Parser string `json:"parser"`
+		*fields
+	}{JSONSchema, jsonParser, (*fields)(lnk)})
+}
+
+// scanRecord is a line written by ScanToJSONL.
+type scanRecord struct {
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+	LNK   *LNK   `json:"lnk,omitempty"`
+}
+
+// ScanToJSONL parses every .lnk file under dir and writes one JSON object per
+// line to w for each of them, holding its path in "path", the shortcut as
+// encoded by MarshalJSON in "lnk", and any error in "error". A shortcut that
+// fails to parse or a directory that cannot be read does not stop the scan,
+// but is written as a record with an error; the fields that were parsed
+// before the error are still included. Only errors writing to w, or the
+// cancellation of ctx, stop it.
+func ScanToJSONL(ctx context.Context, dir string, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	fsys := os.DirFS(dir)
+	return fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		record := scanRecord{Path: filepath.Join(dir, filepath.FromSlash(name))}
+		if err != nil {
+			record.Error = err.Error()
+			return enc.Encode(record)
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(name), ".lnk") {
+			return nil
+		}
+
+		record.LNK, err = ParseFS(fsys, name)
+		if err != nil {
+			record.Error = err.Error()
+		}
+		return enc.Encode(record)
+	})
+}
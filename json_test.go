@@ -0,0 +1,41 @@
+package lnk
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	lnk := load(t, "local.lnk")
+	data, err := json.Marshal(lnk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"_schema":"`+JSONSchema+`","parser":"MS-SHLLINK 3.0",`) {
+		t.Errorf("JSON does not start with the schema and parser: %s", data)
+	}
+
+	var fields struct {
+		CLSID         string
+		LocalBasePath string
+		CreationTime  time.Time
+		EndOffset     int64
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields.CLSID != lnk.CLSID.String() {
+		t.Errorf("CLSID = %q, want %q", fields.CLSID, lnk.CLSID.String())
+	}
+	if fields.LocalBasePath != `C:\test\a.txt` {
+		t.Errorf("LocalBasePath = %q", fields.LocalBasePath)
+	}
+	if _, offset := fields.CreationTime.Zone(); offset != 0 {
+		t.Errorf("CreationTime = %v, want UTC", fields.CreationTime)
+	}
+	if fields.EndOffset != lnk.EndOffset || fields.EndOffset == 0 {
+		t.Errorf("EndOffset = %d, want %d", fields.EndOffset, lnk.EndOffset)
+	}
+}
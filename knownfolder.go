@@ -0,0 +1,155 @@
+package lnk
+
+// csidls maps CSIDL values to the canonical names of the folders they refer
+// to. Names match those of the equivalent KNOWNFOLDERIDs.
+var csidls = map[uint32]string{
+	0x00: "Desktop",
+	0x01: "InternetFolder",
+	0x02: "Programs",
+	0x03: "ControlPanelFolder",
+	0x04: "PrintersFolder",
+	0x05: "Documents",
+	0x06: "Favorites",
+	0x07: "Startup",
+	0x08: "Recent",
+	0x09: "SendTo",
+	0x0a: "RecycleBinFolder",
+	0x0b: "StartMenu",
+	0x0d: "Music",
+	0x0e: "Videos",
+	0x10: "Desktop",
+	0x11: "ComputerFolder",
+	0x12: "NetworkFolder",
+	0x13: "NetHood",
+	0x14: "Fonts",
+	0x15: "Templates",
+	0x16: "CommonStartMenu",
+	0x17: "CommonPrograms",
+	0x18: "CommonStartup",
+	0x19: "PublicDesktop",
+	0x1a: "RoamingAppData",
+	0x1b: "PrintHood",
+	0x1c: "LocalAppData",
+	0x1d: "Startup",
+	0x1e: "CommonStartup",
+	0x1f: "Favorites",
+	0x20: "InternetCache",
+	0x21: "Cookies",
+	0x22: "History",
+	0x23: "ProgramData",
+	0x24: "Windows",
+	0x25: "System",
+	0x26: "ProgramFiles",
+	0x27: "Pictures",
+	0x28: "Profile",
+	0x29: "SystemX86",
+	0x2a: "ProgramFilesX86",
+	0x2b: "ProgramFilesCommon",
+	0x2c: "ProgramFilesCommonX86",
+	0x2d: "CommonTemplates",
+	0x2e: "PublicDocuments",
+	0x2f: "CommonAdminTools",
+	0x30: "AdminTools",
+	0x31: "ConnectionsFolder",
+	0x35: "PublicMusic",
+	0x36: "PublicPictures",
+	0x37: "PublicVideos",
+	0x38: "ResourceDir",
+	0x39: "LocalizedResourcesDir",
+	0x3a: "CommonOEMLinks",
+	0x3b: "CDBurning",
+}
+
+// KNOWNFOLDERIDs of common folders, in their on-disk byte order, for use with
+// IsUnder and LookupKnownFolder.
+var (
+	KnownFolderDesktop        = guid("B4BFCC3A-DB2C-424C-B029-7FE99A87C641")
+	KnownFolderDocuments      = guid("FDD39AD0-238F-46AF-ADB4-6C85480369C7")
+	KnownFolderDownloads      = guid("374DE290-123F-4565-9164-39C4925E467B")
+	KnownFolderMusic          = guid("4BD8D571-6D19-48D3-BE97-422220080E43")
+	KnownFolderPictures       = guid("33E28130-4E1E-4676-835A-98395C3BC3BB")
+	KnownFolderVideos         = guid("18989B1D-99B5-455B-841C-AB7C74E4DDFC")
+	KnownFolderProfile        = guid("5E6C858F-0E22-4760-9AFE-EA3317B67173")
+	KnownFolderRoamingAppData = guid("3EB685DB-65F9-4CF6-A03A-E3EF65729F3D")
+	KnownFolderLocalAppData   = guid("F1B32785-6FBA-4FCF-9D55-7B8E7F157091")
+	KnownFolderProgramData    = guid("62AB5D82-FDC1-4DC3-A9DD-070D1D495D97")
+	KnownFolderProgramFiles   = guid("905E63B6-C1BF-494E-B29C-65B732D3D21A")
+	KnownFolderWindows        = guid("F38BF404-1D43-42F2-9305-67DE0B28FC23")
+	KnownFolderStartMenu      = guid("625B53C3-AB48-4EC1-BA1F-A1EF4146FC19")
+	KnownFolderStartup        = guid("B97D20BB-F46A-4C97-BA10-5E3608430854")
+)
+
+// knownFolders maps KNOWNFOLDERIDs, in their on-disk byte order, to the
+// canonical names of the folders they refer to.
+var knownFolders = map[GUID]string{
+	KnownFolderDesktop:                           "Desktop",
+	KnownFolderDocuments:                         "Documents",
+	KnownFolderDownloads:                         "Downloads",
+	KnownFolderMusic:                             "Music",
+	KnownFolderPictures:                          "Pictures",
+	KnownFolderVideos:                            "Videos",
+	guid("1777F761-68AD-4D8A-87BD-30B759FA33DD"): "Favorites",
+	guid("BFB9D5E0-C6A9-404C-B2B2-AE6DB6AF4968"): "Links",
+	guid("56784854-C6CB-462B-8169-88E350ACB882"): "Contacts",
+	guid("4C5C32FF-BB9D-43B0-B5B4-2D72E54EAAA4"): "SavedGames",
+	guid("AB5FB87B-7CE2-4F83-915D-550846C9537B"): "CameraRoll",
+	guid("B7BEDE81-DF94-4682-A7D8-57A52620B86F"): "Screenshots",
+	guid("A52BBA46-E9E1-435F-B3D9-28DAA648C0F6"): "OneDrive",
+	KnownFolderProfile:                           "Profile",
+	guid("0762D272-C50A-4BB0-A382-697DCD729B80"): "UserProfiles",
+	KnownFolderRoamingAppData:                    "RoamingAppData",
+	KnownFolderLocalAppData:                      "LocalAppData",
+	guid("A520A1A4-1780-4FF6-BD18-167343C5AF16"): "LocalAppDataLow",
+	KnownFolderProgramData:                       "ProgramData",
+	KnownFolderProgramFiles:                      "ProgramFiles",
+	guid("7C5A40EF-A0FB-4BFC-874A-C0F2E0B9FA8E"): "ProgramFilesX86",
+	guid("6D809377-6AF0-444B-8957-A3773F02200E"): "ProgramFilesX64",
+	guid("F7F1ED05-9F6D-47A2-AAAE-29D317C6F066"): "ProgramFilesCommon",
+	guid("DE974D24-D9C6-4D3E-BF91-F4455120B917"): "ProgramFilesCommonX86",
+	KnownFolderWindows:                           "Windows",
+	guid("1AC14E77-02E7-4E5D-B744-2EB1AE5198B7"): "System",
+	guid("D65231B0-B2F1-4857-A4CE-A8E7C6EA7D27"): "SystemX86",
+	guid("FD228CB7-AE11-4AE3-864C-16F3910AB8FE"): "Fonts",
+	KnownFolderStartMenu:                         "StartMenu",
+	guid("A77F5D77-2E2B-44C3-A6A2-ABA601054A51"): "Programs",
+	KnownFolderStartup:                           "Startup",
+	guid("A4115719-D62E-491D-AA7C-E74B8BE3B067"): "CommonStartMenu",
+	guid("0139D44E-6AFE-49F2-8690-3DAFCAE6FFB8"): "CommonPrograms",
+	guid("82A5EA35-D9CD-47C5-9629-E15D2F714E6E"): "CommonStartup",
+	guid("DFDF76A2-C82A-4D63-906A-5644AC457385"): "Public",
+	guid("C4AA340D-F20F-4863-AFEF-F87EF2E6BA25"): "PublicDesktop",
+	guid("ED4824AF-DCE4-45A8-81E2-FC7965083634"): "PublicDocuments",
+	guid("AE50C081-EBD2-438A-8655-8A092E34987A"): "Recent",
+	guid("8983036C-27C0-404B-8F08-102D10DCFD74"): "SendTo",
+	guid("A63293E8-664E-48DB-A079-DF759E0509F7"): "Templates",
+	guid("52A4F021-7B75-48A9-9F6B-4B87A210BC8F"): "QuickLaunch",
+	guid("9E3995AB-1F9C-4F13-B827-48B24B6C7174"): "UserPinned",
+	guid("724EF170-A42D-4FEF-9F26-B60E846FBA4F"): "AdminTools",
+	guid("D0384E7D-BAC3-4797-8F14-CBA229B392B5"): "CommonAdminTools",
+	guid("1B3EA5DC-B587-4786-B4EF-BD1DC332AEAE"): "Libraries",
+	guid("0AC0837C-BBF8-452A-850D-79D08E667CA7"): "ComputerFolder",
+	guid("D20BEEC4-5CA8-4905-AE3B-BF251EA09B53"): "NetworkFolder",
+	guid("82A74AEB-AEB4-465C-A014-D097EE346D63"): "ControlPanelFolder",
+	guid("B7534046-3ECB-4C18-BE4E-64CD4CB7D6AC"): "RecycleBinFolder",
+	guid("1E87508D-89C2-42F0-8A7E-645A0F50CA58"): "AppsFolder",
+}
+
+// LookupCSIDL returns the canonical name of the folder identified by a CSIDL.
+func LookupCSIDL(csidl uint32) (string, bool) {
+	name, ok := csidls[csidl]
+	return name, ok
+}
+
+// LookupKnownFolder returns the canonical name of the folder identified by a
+// KNOWNFOLDERID.
+func LookupKnownFolder(id GUID) (string, bool) {
+	name, ok := knownFolders[id]
+	return name, ok
+}
+
+// IsUnder reports whether the KnownFolderDataBlock identifies folder, e.g.
+// KnownFolderDownloads, meaning that the target is within it.
+func (lnk *LNK) IsUnder(folder GUID) bool {
+	lnk.LoadExtraData()
+	return lnk.KnownFolder != nil && lnk.KnownFolder.ID == folder
+}
@@ -0,0 +1,23 @@
+package lnk
+
+import "testing"
+
+func TestIsUnder(t *testing.T) {
+	lnk := load(t, "knownfolder.lnk")
+	if !lnk.IsUnder(KnownFolderDownloads) {
+		t.Error("IsUnder(KnownFolderDownloads) = false")
+	}
+	if lnk.IsUnder(KnownFolderDesktop) {
+		t.Error("IsUnder(KnownFolderDesktop) = true")
+	}
+	if name, ok := LookupKnownFolder(lnk.KnownFolder.ID); !ok || name != "Downloads" {
+		t.Errorf("LookupKnownFolder() = %q, %v, want %q", name, ok, "Downloads")
+	}
+	if child, err := lnk.KnownFolderChildPath(); err != nil || child != "report.pdf" {
+		t.Errorf("KnownFolderChildPath() = %q, %v, want %q", child, err, "report.pdf")
+	}
+
+	if load(t, "uwp.lnk").IsUnder(KnownFolderDownloads) {
+		t.Error("IsUnder(KnownFolderDownloads) = true without a KnownFolderDataBlock")
+	}
+}
@@ -0,0 +1,316 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoTarget is returned when a shortcut has no structure that the target
+// can be resolved from.
+var ErrNoTarget = errors.New("no target")
+
+// DriveSerial returns DriveSerialNumber formatted the way the vol command
+// displays it, e.g. "1A2B-3C4D".
+func (lnk *LNK) DriveSerial() string {
+	return fmt.Sprintf("%04X-%04X", lnk.DriveSerialNumber>>16, lnk.DriveSerialNumber&0xffff)
+}
+
+// LinkInfoIgnored reports whether the LinkInfo must be ignored when resolving
+// the target because ForceNoLinkInfo is set, even if HasLinkInfo is also set.
+func (lnk *LNK) LinkInfoIgnored() bool {
+	return lnk.ForceNoLinkInfo
+}
+
+// ResolveTarget returns the path of the target. It is taken from the LinkInfo,
+// whether local, as LocalBasePath followed by CommonPathSuffix, or on a
+// network share, unless it is ignored, preferring the Unicode variants of
+// those strings where they are present, then from the IDList, and finally from
+// the EnvironmentVariableDataBlock, whose path is returned without expanding
+// its environment variables. If PrefersEnvironmentPath, the
+// EnvironmentVariableDataBlock comes first instead.
+func (lnk *LNK) ResolveTarget() (string, error) {
+	lnk.LoadExtraData()
+	if lnk.PrefersEnvironmentPath() && lnk.EnvironmentVariable != nil && lnk.EnvironmentVariable.Target() != "" {
+		return lnk.EnvironmentVariable.Target(), nil
+	}
+	if lnk.HasLinkInfo && !lnk.LinkInfoIgnored() && lnk.VolumeIDAndLocalBasePath && lnk.localBasePath() != "" {
+		return lnk.localBasePath() + lnk.commonPathSuffix(), nil
+	}
+	if lnk.IsNetworkTarget() && !lnk.LinkInfoIgnored() && lnk.netName() != "" {
+		return lnk.UNCPath(), nil
+	}
+
+	if len(lnk.IDListBytes) != 0 || len(lnk.VistaAndAboveIDList) != 0 {
+		path, err := lnk.IDListPath()
+		if err == nil && path != "" {
+			return path, nil
+		}
+		if err != nil && lnk.EnvironmentVariable == nil {
+			return "", err
+		}
+	}
+
+	if lnk.EnvironmentVariable != nil && lnk.EnvironmentVariable.Target() != "" {
+		return lnk.EnvironmentVariable.Target(), nil
+	}
+
+	return "", ErrNoTarget
+}
+
+// IsNetworkTarget reports whether the LinkInfo locates the target on a network
+// share rather than on a local volume.
+func (lnk *LNK) IsNetworkTarget() bool {
+	return lnk.HasLinkInfo && lnk.CommonNetworkRelativeLinkAndPathSuffix
+}
+
+// BothLocalAndNetwork reports whether the LinkInfo locates the target both on
+// a local volume and on a network share, as it does for a file on a mapped
+// drive. LocalBasePath and NetName are then both set, and ResolveTarget
+// prefers the former.
+func (lnk *LNK) BothLocalAndNetwork() bool {
+	return lnk.HasLinkInfo && lnk.VolumeIDAndLocalBasePath && lnk.CommonNetworkRelativeLinkAndPathSuffix
+}
+
+// UNCPath returns the path of a network target, which is NetName followed by
+// CommonPathSuffix, e.g. `\\server\share\file.txt`, preferring their Unicode
+// variants where they are present. It returns an empty string if the target
+// is not on a network share.
+func (lnk *LNK) UNCPath() string {
+	if !lnk.IsNetworkTarget() {
+		return ""
+	}
+	netName, suffix := lnk.netName(), lnk.commonPathSuffix()
+	if suffix == "" || strings.HasSuffix(netName, `\`) {
+		return netName + suffix
+	}
+	return netName + `\` + suffix
+}
+
+// localBasePath returns LocalBasePathUnicode, or LocalBasePath if it is
+// absent, as the ANSI variant loses the characters missing from the code page.
+func (lnk *LNK) localBasePath() string {
+	return preferUnicode(lnk.LocalBasePath, lnk.LocalBasePathUnicode)
+}
+
+// netName returns NetNameUnicode, or NetName if it is absent.
+func (lnk *LNK) netName() string {
+	return preferUnicode(lnk.NetName, lnk.NetNameUnicode)
+}
+
+// commonPathSuffix returns CommonPathSuffixUnicode, or CommonPathSuffix if it
+// is absent.
+func (lnk *LNK) commonPathSuffix() string {
+	return preferUnicode(lnk.CommonPathSuffix, lnk.CommonPathSuffixUnicode)
+}
+
+func preferUnicode(str, unicode string) string {
+	if unicode != "" {
+		return unicode
+	}
+	return str
+}
+
+// PreferredStrings sets the LinkInfo strings that have both ANSI and Unicode
+// variants, VolumeLabel, LocalBasePath, NetName, DeviceName and
+// CommonPathSuffix, to their Unicode variant wherever it is present. The ANSI
+// variants depend on the code page of the system that created the shortcut,
+// so they can be garbled elsewhere. The original values are kept in the ANSI
+// fields.
+func (lnk *LNK) PreferredStrings() {
+	if lnk.VolumeLabelUnicode != "" {
+		lnk.VolumeLabel = lnk.VolumeLabelUnicode
+	}
+	if lnk.LocalBasePathUnicode != "" {
+		lnk.LocalBasePath = lnk.LocalBasePathUnicode
+	}
+	if lnk.NetNameUnicode != "" {
+		lnk.NetName = lnk.NetNameUnicode
+	}
+	if lnk.DeviceNameUnicode != "" {
+		lnk.DeviceName = lnk.DeviceNameUnicode
+	}
+	if lnk.CommonPathSuffixUnicode != "" {
+		lnk.CommonPathSuffix = lnk.CommonPathSuffixUnicode
+	}
+}
+
+// executableExtensions are the extensions of files that run code when they
+// are opened.
+var executableExtensions = map[string]bool{
+	".bat": true, ".cmd": true, ".com": true, ".cpl": true, ".exe": true,
+	".hta": true, ".js": true, ".jse": true, ".msc": true, ".msi": true,
+	".pif": true, ".ps1": true, ".scr": true, ".vbe": true, ".vbs": true,
+	".wsf": true, ".wsh": true,
+}
+
+// TargetExtension returns the lowercase extension of the path returned by
+// ResolveTarget, such as ".exe". It returns an empty string if the target is a
+// directory, cannot be resolved to a path (as for packaged applications), or
+// has no extension.
+func (lnk *LNK) TargetExtension() string {
+	if lnk.Directory {
+		return ""
+	}
+	path, err := lnk.ResolveTarget()
+	if err != nil {
+		return ""
+	}
+
+	name := path[strings.LastIndexAny(path, `\/`)+1:]
+	i := strings.LastIndexByte(name, '.')
+	if i == -1 {
+		return ""
+	}
+	return strings.ToLower(name[i:])
+}
+
+// TargetIsExecutable reports whether TargetExtension is that of a program or
+// script, such as ".exe", ".bat" or ".ps1".
+func (lnk *LNK) TargetIsExecutable() bool {
+	return executableExtensions[lnk.TargetExtension()]
+}
+
+// TargetsShortcut reports whether the target is itself a shortcut, i.e.
+// TargetExtension is ".lnk". Resolvers that follow such chains should cap
+// their depth, as shortcuts can refer to each other in a loop.
+func (lnk *LNK) TargetsShortcut() bool {
+	return lnk.TargetExtension() == ".lnk"
+}
+
+// AllowsShortcutTarget reports whether the shortcut may target another
+// shortcut, which is otherwise not followed by the shell. It is the
+// AllowLinkToLink LinkFlag, bit 23 (0x00800000).
+func (lnk *LNK) AllowsShortcutTarget() bool {
+	return lnk.AllowLinkToLink
+}
+
+// PrefersEnvironmentPath reports whether the path in the
+// EnvironmentVariableDataBlock takes precedence over the other locations of
+// the target when the shortcut is resolved. It is the PreferEnvironmentPath
+// LinkFlag, bit 25 (0x02000000).
+func (lnk *LNK) PrefersEnvironmentPath() bool {
+	return lnk.PreferEnvironmentPath
+}
+
+// NormalizedTarget returns the path returned by ResolveTarget in a canonical
+// form for comparing shortcuts: forward slashes become backslashes, repeated
+// separators are collapsed, "." and ".." segments are resolved, trailing
+// separators are removed and the drive letter is uppercased, e.g.
+// `c:\a\..\b\.\c.exe` becomes `C:\b\c.exe`. It is purely lexical, so it does
+// not follow reparse points, and it returns an empty string if ResolveTarget
+// fails.
+func (lnk *LNK) NormalizedTarget() string {
+	path, err := lnk.ResolveTarget()
+	if err != nil {
+		return ""
+	}
+	return normalizePath(path)
+}
+
+// TargetDir returns the folder that contains the target, which is
+// NormalizedTarget up to its last separator, e.g. `C:\Program Files\App` for
+// `C:\Program Files\App\app.exe`. The root of a drive keeps its separator, so
+// the folder of `C:\app.exe` is `C:\`, and the root of a UNC path is the
+// share, so the folder of `\\server\share\tool.exe` is `\\server\share`. It
+// returns an empty string if the target is itself a root, has no folder, or
+// cannot be resolved.
+func (lnk *LNK) TargetDir() string {
+	return parentDir(lnk.NormalizedTarget())
+}
+
+// TargetMatches reports whether the target is the file at path, comparing
+// NormalizedTarget with path normalized in the same way, and ignoring case as
+// Windows does, so that `c:/program files/app/` matches
+// `C:\Program Files\App`. It returns false if the target cannot be resolved.
+func (lnk *LNK) TargetMatches(path string) bool {
+	target := lnk.NormalizedTarget()
+	return target != "" && strings.EqualFold(target, normalizePath(path))
+}
+
+// parentDir implements TargetDir for a path cleaned by normalizePath.
+func parentDir(path string) string {
+	var root string
+	switch {
+	case len(path) >= 3 && path[1] == ':':
+		root = path[:3]
+	case strings.HasPrefix(path, `\\`):
+		// the server and share names are part of the root
+		parts := strings.SplitN(path[2:], `\`, 3)
+		if len(parts) < 3 {
+			return ""
+		}
+		root = `\\` + parts[0] + `\` + parts[1] + `\`
+	case strings.HasPrefix(path, `\`):
+		root = `\`
+	}
+
+	rest := path[len(root):]
+	i := strings.LastIndexByte(rest, '\\')
+	switch {
+	case rest == "":
+		return ""
+	case i != -1:
+		return root + rest[:i]
+	case strings.HasPrefix(root, `\\`):
+		return strings.TrimSuffix(root, `\`)
+	default:
+		return root
+	}
+}
+
+// ResolveRelative joins base with RelativePath and cleans the result as
+// NormalizedTarget does, e.g. `..\bin\app.exe` against `C:\Tools\Shortcuts`
+// becomes `C:\Tools\bin\app.exe`. RelativePath is relative to the folder that
+// contains the shortcut, so base should be that folder, not the path of the
+// shortcut itself nor WorkingDir. It returns an empty string if there is no
+// RelativePath, and RelativePath cleaned on its own if it is absolute.
+func (lnk *LNK) ResolveRelative(base string) string {
+	if !lnk.HasRelativePath || lnk.RelativePath == "" {
+		return ""
+	}
+	path := lnk.RelativePath
+	if !isAbsolutePath(path) {
+		path = base + `\` + path
+	}
+	return normalizePath(path)
+}
+
+// isAbsolutePath reports whether path starts with a drive letter or a
+// separator.
+func isAbsolutePath(path string) bool {
+	return len(path) >= 2 && path[1] == ':' || strings.HasPrefix(path, `\`) || strings.HasPrefix(path, "/")
+}
+
+// normalizePath implements NormalizedTarget.
+func normalizePath(path string) string {
+	path = strings.ReplaceAll(path, "/", `\`)
+
+	// the root, which ".." segments cannot go above
+	var root string
+	var fixed int
+	switch {
+	case len(path) >= 2 && path[1] == ':':
+		root = strings.ToUpper(path[:1]) + `:\`
+		path = path[2:]
+	case strings.HasPrefix(path, `\\`):
+		// the server and share names are part of the root
+		root = `\\`
+		fixed = 2
+	case strings.HasPrefix(path, `\`):
+		root = `\`
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(path, `\`) {
+		switch {
+		case segment == "" || segment == ".":
+		case segment == ".." && len(segments) > fixed && segments[len(segments)-1] != "..":
+			segments = segments[:len(segments)-1]
+		case segment == ".." && root != "":
+		default:
+			segments = append(segments, segment)
+		}
+	}
+	return root + strings.Join(segments, `\`)
+}
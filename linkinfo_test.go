@@ -0,0 +1,490 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestResolveTarget(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"local.lnk", `C:\test\a.txt`},
+		{"forcenoli.lnk", `C:\test\a.txt`},
+		{"forcenoli_env.lnk", `%windir%\notepad.exe`},
+		{"emoji_li.lnk", "C:\\\U0001F600.txt"},
+	}
+	for _, test := range tests {
+		lnk := load(t, test.file)
+		got, err := lnk.ResolveTarget()
+		if err != nil || got != test.want {
+			t.Errorf("%s: ResolveTarget() = %q, %v, want %q", test.file, got, err, test.want)
+		}
+	}
+}
+
+func TestForceNoLinkInfo(t *testing.T) {
+	lnk := load(t, "forcenoli.lnk")
+	if !lnk.LinkInfoIgnored() {
+		t.Error("LinkInfoIgnored() = false")
+	}
+	// the LinkInfo is still read, so the sections that follow stay aligned
+	if lnk.LocalBasePath != `D:\wrong.txt` || lnk.EnvironmentVariable == nil || len(lnk.UnknownBlocks) != 0 {
+		t.Errorf("LocalBasePath = %q, EnvironmentVariable = %v, %d unknown blocks", lnk.LocalBasePath, lnk.EnvironmentVariable, len(lnk.UnknownBlocks))
+	}
+}
+
+func TestResolveTargetCommonPathSuffix(t *testing.T) {
+	lnk := &LNK{
+		HasLinkInfo:              true,
+		IsUnicode:                true,
+		VolumeIDAndLocalBasePath: true,
+		LocalBasePath:            `C:\Users\`,
+		CommonPathSuffix:         `Public\report.pdf`,
+	}
+	const want = `C:\Users\Public\report.pdf`
+	if got, err := lnk.ResolveTarget(); err != nil || got != want {
+		t.Errorf("ResolveTarget() = %q, %v, want %q", got, err, want)
+	}
+
+	var buf bytes.Buffer
+	if _, err := lnk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := QuickTarget(bytes.NewReader(buf.Bytes())); err != nil || got != want {
+		t.Errorf("QuickTarget() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestResolveTargetUnicode(t *testing.T) {
+	// the ANSI strings lost the characters missing from the code page
+	lnk := &LNK{
+		HasLinkInfo:                            true,
+		IsUnicode:                              true,
+		CommonNetworkRelativeLinkAndPathSuffix: true,
+		NetName:                                `\\serveur\donn?es`,
+		NetNameUnicode:                         `\\serveur\données`,
+		CommonPathSuffix:                       `caf?.txt`,
+		CommonPathSuffixUnicode:                `café.txt`,
+	}
+	const want = `\\serveur\données\café.txt`
+	if got, err := lnk.ResolveTarget(); err != nil || got != want {
+		t.Errorf("ResolveTarget() = %q, %v, want %q", got, err, want)
+	}
+
+	var buf bytes.Buffer
+	if _, err := lnk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := QuickTarget(bytes.NewReader(buf.Bytes())); err != nil || got != want {
+		t.Errorf("QuickTarget() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestLinkInfoEnlargedHeader(t *testing.T) {
+	b := NewBuilder()
+	b.SetVolume(0x1234, "VOL")
+	if err := b.SetTargetPath(`C:\x\y.exe`); err != nil {
+		t.Fatal(err)
+	}
+	b.SetArguments("-a")
+	lnk := b.Build()
+	linkInfo := lnk.linkInfo()
+	var buf bytes.Buffer
+	if _, err := lnk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// a header of 0x30 bytes, with the structures moved after it
+	const extra = 0x14
+	enlarged := append(append(append([]byte(nil), linkInfo[:0x1c]...), make([]byte, extra)...), linkInfo[0x1c:]...)
+	endianness.PutUint32(enlarged[0:], uint32(len(enlarged)))
+	endianness.PutUint32(enlarged[4:], 0x1c+extra)
+	for _, offset := range []int{12, 16, 24} {
+		if value := endianness.Uint32(enlarged[offset:]); value != 0 {
+			endianness.PutUint32(enlarged[offset:], value+extra)
+		}
+	}
+	modified := append(append(append([]byte(nil), data[:76]...), enlarged...), data[76+len(linkInfo):]...)
+
+	got, err := ParseWithOptions(bytes.NewReader(modified), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LocalBasePath != `C:\x\y.exe` || got.VolumeLabel != "VOL" || got.Arguments != "-a" {
+		t.Errorf("LocalBasePath = %q, VolumeLabel = %q, Arguments = %q", got.LocalBasePath, got.VolumeLabel, got.Arguments)
+	}
+
+	// CommonPathSuffix within the header is rejected, even when not strict
+	endianness.PutUint32(modified[76+24:], 0x20)
+	for _, opts := range []ParseOptions{{}, {Strict: true}} {
+		_, err := ParseWithOptions(bytes.NewReader(modified), opts)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("strict %v: error = %v, want a ParseError", opts.Strict, err)
+			continue
+		}
+		if parseErr.Offset != 76+0x20 {
+			t.Errorf("strict %v: Offset = %d, want %d", opts.Strict, parseErr.Offset, 76+0x20)
+		}
+	}
+}
+
+func TestVolumeLabelWithoutTerminator(t *testing.T) {
+	// the label fills the VolumeID, and LocalBasePath immediately follows it
+	lnk := load(t, "label.lnk")
+	if lnk.VolumeLabel != "DATA" {
+		t.Errorf("VolumeLabel = %q, want DATA", lnk.VolumeLabel)
+	}
+	if lnk.LocalBasePath != `C:\x.exe` {
+		t.Errorf("LocalBasePath = %q", lnk.LocalBasePath)
+	}
+}
+
+func TestNetworkTarget(t *testing.T) {
+	lnk := load(t, "unc.lnk")
+	if !lnk.IsNetworkTarget() {
+		t.Error("IsNetworkTarget() = false")
+	}
+	const want = `\\server\share\file.txt`
+	if got := lnk.UNCPath(); got != want {
+		t.Errorf("UNCPath() = %q, want %q", got, want)
+	}
+
+	local := load(t, "local.lnk")
+	if local.IsNetworkTarget() || local.UNCPath() != "" {
+		t.Errorf("local shortcut: IsNetworkTarget() = %v, UNCPath() = %q", local.IsNetworkTarget(), local.UNCPath())
+	}
+}
+
+func TestLinkInfoMinimalHeader(t *testing.T) {
+	// a 28-byte header with LocalBasePath stored before the VolumeID
+	lnk := load(t, "li28.lnk")
+	if lnk.LocalBasePath != `C:\odd.txt` || lnk.LocalBasePathUnicode != "" {
+		t.Errorf("LocalBasePath = %q, LocalBasePathUnicode = %q", lnk.LocalBasePath, lnk.LocalBasePathUnicode)
+	}
+	if lnk.VolumeLabel != "DATA" || lnk.DriveSerialNumber != 0x1234abcd {
+		t.Errorf("VolumeLabel = %q, DriveSerialNumber = 0x%x", lnk.VolumeLabel, lnk.DriveSerialNumber)
+	}
+}
+
+func TestLinkInfoZeroUnicodeOffsets(t *testing.T) {
+	// a 36-byte header whose Unicode offsets are zero
+	lnk, err := ParseWithOptions(bytes.NewReader(readTestdata(t, "li24zero.lnk")), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.LocalBasePath != `C:\z.txt` || lnk.VolumeLabel != "DATA" {
+		t.Errorf("LocalBasePath = %q, VolumeLabel = %q", lnk.LocalBasePath, lnk.VolumeLabel)
+	}
+	if lnk.LocalBasePathUnicode != "" || lnk.CommonPathSuffixUnicode != "" {
+		t.Errorf("LocalBasePathUnicode = %q, CommonPathSuffixUnicode = %q, want none", lnk.LocalBasePathUnicode, lnk.CommonPathSuffixUnicode)
+	}
+	lnk.PreferredStrings()
+	if lnk.LocalBasePath != `C:\z.txt` {
+		t.Errorf("LocalBasePath = %q after PreferredStrings", lnk.LocalBasePath)
+	}
+}
+
+func TestNetworkLinkFlags(t *testing.T) {
+	tests := []struct {
+		file                      string
+		validDevice, validNetType bool
+		deviceName                string
+		providerType              uint32
+	}{
+		// the DeviceName is stored but not marked valid
+		{"unc_nodev.lnk", false, true, "", 0x20000},
+		{"unc_dev.lnk", true, true, "Z:", 0x20000},
+	}
+	for _, test := range tests {
+		lnk := load(t, test.file)
+		if lnk.ValidDevice != test.validDevice || lnk.ValidNetType != test.validNetType {
+			t.Errorf("%s: ValidDevice = %v, ValidNetType = %v, want %v, %v", test.file, lnk.ValidDevice, lnk.ValidNetType, test.validDevice, test.validNetType)
+		}
+		if lnk.DeviceName != test.deviceName || lnk.NetworkProviderType != test.providerType {
+			t.Errorf("%s: DeviceName = %q, NetworkProviderType = %#x, want %q, %#x", test.file, lnk.DeviceName, lnk.NetworkProviderType, test.deviceName, test.providerType)
+		}
+		if lnk.NetName != `\\server\share` {
+			t.Errorf("%s: NetName = %q", test.file, lnk.NetName)
+		}
+	}
+}
+
+func TestForceNoLinkInfoStrict(t *testing.T) {
+	for _, name := range []string{"forcenoli.lnk", "forcenoli_env.lnk"} {
+		lnk, err := ParseWithOptions(bytes.NewReader(readTestdata(t, name)), ParseOptions{Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !lnk.HasLinkInfo || !lnk.LinkInfoIgnored() {
+			t.Errorf("%s: HasLinkInfo = %v, LinkInfoIgnored() = %v", name, lnk.HasLinkInfo, lnk.LinkInfoIgnored())
+		}
+		if got := lnk.EnvironmentVariable.Target(); got != `%windir%\notepad.exe` {
+			t.Errorf("%s: EnvironmentVariable.Target() = %q", name, got)
+		}
+		if _, err := QuickTarget(bytes.NewReader(readTestdata(t, name))); err != ErrNoTarget {
+			t.Errorf("%s: QuickTarget() error = %v, want %v", name, err, ErrNoTarget)
+		}
+	}
+}
+
+func TestVolumeLabelUnicode(t *testing.T) {
+	lnk := load(t, "cyrillic_label.lnk")
+	const want = "Документы"
+	if lnk.VolumeLabelUnicode != want || lnk.VolumeLabel != want {
+		t.Errorf("VolumeLabelUnicode = %q, VolumeLabel = %q, want %q", lnk.VolumeLabelUnicode, lnk.VolumeLabel, want)
+	}
+	// a Unicode label replaces the ANSI one
+	if lnk.VolumeLabelANSI != "" {
+		t.Errorf("VolumeLabelANSI = %q, want none", lnk.VolumeLabelANSI)
+	}
+}
+
+func TestTargetsShortcut(t *testing.T) {
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\Users\Public\Desktop\Other.LNK`); err != nil {
+		t.Fatal(err)
+	}
+	lnk := roundTrip(t, b)
+	if !lnk.TargetsShortcut() {
+		t.Errorf("TargetsShortcut() = false for %q", lnk.LocalBasePath)
+	}
+	if lnk.AllowsShortcutTarget() {
+		t.Error("AllowsShortcutTarget() = true without AllowLinkToLink")
+	}
+	lnk.AllowLinkToLink = true
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk, err = Parse(bytes.NewReader(data)); err != nil || !lnk.AllowsShortcutTarget() {
+		t.Errorf("AllowsShortcutTarget() = false with AllowLinkToLink, %v", err)
+	}
+
+	if load(t, "local.lnk").TargetsShortcut() {
+		t.Error("TargetsShortcut() = true for a .txt target")
+	}
+}
+
+func TestVolumeLabelTrailingData(t *testing.T) {
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\x.exe`); err != nil {
+		t.Fatal(err)
+	}
+	lnk := b.Build()
+	lnk.VolumeLabel = "AB\x00XY"
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the label ends at its first NUL, and the bytes after it are reported
+	lnk, err = Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.VolumeLabel != "AB" || len(lnk.Warnings()) != 1 {
+		t.Errorf("VolumeLabel = %q, Warnings() = %q, want %q and one warning", lnk.VolumeLabel, lnk.Warnings(), "AB")
+	}
+	if _, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true}); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("strict: error = %v, want %v", err, ErrTrailingData)
+	}
+}
+
+func TestBothLocalAndNetwork(t *testing.T) {
+	lnk, err := ParseWithOptions(bytes.NewReader(readTestdata(t, "mapped.lnk")), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lnk.BothLocalAndNetwork() {
+		t.Error("BothLocalAndNetwork() = false")
+	}
+	if lnk.LocalBasePath != `Z:\` || lnk.VolumeLabel != "SHARE" || lnk.NetName != `\\server\share` || lnk.DeviceName != "Z:" {
+		t.Errorf("LocalBasePath = %q, VolumeLabel = %q, NetName = %q, DeviceName = %q", lnk.LocalBasePath, lnk.VolumeLabel, lnk.NetName, lnk.DeviceName)
+	}
+	// the local path is preferred
+	if got, err := lnk.ResolveTarget(); err != nil || got != `Z:\docs\f.txt` {
+		t.Errorf("ResolveTarget() = %q, %v, want %q", got, err, `Z:\docs\f.txt`)
+	}
+	if got := lnk.UNCPath(); got != `\\server\share\docs\f.txt` {
+		t.Errorf("UNCPath() = %q, want %q", got, `\\server\share\docs\f.txt`)
+	}
+	// the ExtraData that follows is aligned
+	if lnk.EnvironmentVariable == nil || lnk.EnvironmentVariable.Target() != `Z:\docs\f.txt` {
+		t.Errorf("EnvironmentVariable = %+v", lnk.EnvironmentVariable)
+	}
+
+	for _, name := range []string{"local.lnk", "unc.lnk"} {
+		if load(t, name).BothLocalAndNetwork() {
+			t.Errorf("%s: BothLocalAndNetwork() = true", name)
+		}
+	}
+}
+
+func TestNormalizedTarget(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\a\..\b\.\c.exe`, `C:\b\c.exe`},
+		{`c:/x//y/`, `C:\x\y`},
+		{`c:\..\x`, `C:\x`},
+		{`C:`, `C:\`},
+		{`\\srv\share\..\..\a`, `\\srv\share\a`},
+		{`%windir%\..\..\notepad.exe`, `..\notepad.exe`},
+	}
+	for _, test := range tests {
+		lnk := &LNK{HasLinkInfo: true, VolumeIDAndLocalBasePath: true, LocalBasePath: test.path}
+		if got := lnk.NormalizedTarget(); got != test.want {
+			t.Errorf("NormalizedTarget() of %q = %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	if got := (&LNK{}).NormalizedTarget(); got != "" {
+		t.Errorf("NormalizedTarget() = %q without a target", got)
+	}
+}
+
+func TestResolveRelative(t *testing.T) {
+	tests := []struct {
+		relativePath string
+		base         string
+		want         string
+	}{
+		{`..\bin\app.exe`, `C:\Tools\Shortcuts`, `C:\Tools\bin\app.exe`},
+		{`.\a.txt`, `C:\test\`, `C:\test\a.txt`},
+		{`..\..\..\x.exe`, `C:\a`, `C:\x.exe`},
+		{`..\tool.exe`, `\\server\share\links`, `\\server\share\tool.exe`},
+		// absolute paths ignore the base
+		{`D:\x\..\y`, `C:\a`, `D:\y`},
+		{``, `C:\a`, ``},
+	}
+	for _, test := range tests {
+		lnk := &LNK{HasRelativePath: test.relativePath != "", RelativePath: test.relativePath}
+		if got := lnk.ResolveRelative(test.base); got != test.want {
+			t.Errorf("ResolveRelative(%q) of %q = %q, want %q", test.base, test.relativePath, got, test.want)
+		}
+	}
+
+	if got := load(t, "local.lnk").ResolveRelative(`C:\test`); got != `C:\test\a.txt` {
+		t.Errorf("ResolveRelative() = %q, want %q", got, `C:\test\a.txt`)
+	}
+}
+
+func TestTargetDir(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\Program Files\App\app.exe`, `C:\Program Files\App`},
+		{`C:\app.exe`, `C:\`},
+		{`C:\`, ``},
+		{`c:\a\..\b\c.exe`, `C:\b`},
+		{`\\server\share\tool.exe`, `\\server\share`},
+		{`\\server\share\a\tool.exe`, `\\server\share\a`},
+		{`\\server\share`, ``},
+		{`\x.exe`, `\`},
+		{`app.exe`, ``},
+	}
+	for _, test := range tests {
+		lnk := &LNK{HasLinkInfo: true, VolumeIDAndLocalBasePath: true, LocalBasePath: test.path}
+		if got := lnk.TargetDir(); got != test.want {
+			t.Errorf("TargetDir() of %q = %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	if got := load(t, "unc.lnk").TargetDir(); got != `\\server\share` {
+		t.Errorf("TargetDir() = %q, want %q", got, `\\server\share`)
+	}
+}
+
+func TestPrefersEnvironmentPath(t *testing.T) {
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\Program Files\App\app.exe`); err != nil {
+		t.Fatal(err)
+	}
+	b.AddEnvironmentVariable(`%ProgramFiles%\App\app.exe`, `%ProgramFiles%\App\app.exe`)
+	lnk := roundTrip(t, b)
+	if lnk.PrefersEnvironmentPath() {
+		t.Error("PrefersEnvironmentPath() = true without PreferEnvironmentPath")
+	}
+	if got, err := lnk.ResolveTarget(); err != nil || got != `C:\Program Files\App\app.exe` {
+		t.Errorf("ResolveTarget() = %q, %v, want the LinkInfo path", got, err)
+	}
+
+	// both a LinkInfo and an EnvironmentVariableDataBlock, with the flag set
+	lnk.PreferEnvironmentPath = true
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk, err = Parse(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !lnk.PrefersEnvironmentPath() || lnk.LinkFlags&0x02000000 == 0 {
+		t.Errorf("PrefersEnvironmentPath() = false, LinkFlags = %#x", lnk.LinkFlags)
+	}
+	if got, err := lnk.ResolveTarget(); err != nil || got != `%ProgramFiles%\App\app.exe` {
+		t.Errorf("ResolveTarget() = %q, %v, want the environment path", got, err)
+	}
+}
+
+func TestTargetMatches(t *testing.T) {
+	lnk := &LNK{HasLinkInfo: true, VolumeIDAndLocalBasePath: true, LocalBasePath: `C:\Program Files\App`}
+	for _, path := range []string{
+		`C:\Program Files\App`,
+		`c:/program files/app/`,
+		`C:\\Program Files\.\x\..\App\\`,
+		`c:\PROGRAM FILES\app`,
+	} {
+		if !lnk.TargetMatches(path) {
+			t.Errorf("TargetMatches(%q) = false", path)
+		}
+	}
+	for _, path := range []string{`C:\Program Files\App2`, `D:\Program Files\App`, `C:\Program Files`, ``} {
+		if lnk.TargetMatches(path) {
+			t.Errorf("TargetMatches(%q) = true", path)
+		}
+	}
+
+	if (&LNK{}).TargetMatches("") {
+		t.Error("TargetMatches() = true without a target")
+	}
+	if !load(t, "unc.lnk").TargetMatches(`\\SERVER\share\.\FILE.TXT`) {
+		t.Error("TargetMatches() = false for a UNC path")
+	}
+}
+
+func TestLocalBasePathWithoutVolumeID(t *testing.T) {
+	data := append([]byte(nil), readTestdata(t, "local.lnk")...)
+	// the LinkInfo at 283, with VolumeIDAndLocalBasePath and the
+	// VolumeIDOffset cleared but the LocalBasePathOffset kept
+	linkInfo := data[283:]
+	endianness.PutUint32(linkInfo[8:], 0)
+	endianness.PutUint32(linkInfo[12:], 0)
+
+	lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.VolumeIDAndLocalBasePath || lnk.VolumeLabel != "" || lnk.DriveSerialNumber != 0 {
+		t.Errorf("VolumeIDAndLocalBasePath = %v, VolumeLabel = %q, DriveSerialNumber = 0x%x, want no VolumeID", lnk.VolumeIDAndLocalBasePath, lnk.VolumeLabel, lnk.DriveSerialNumber)
+	}
+	if lnk.LocalBasePath != `C:\test\a.txt` {
+		t.Errorf("LocalBasePath = %q, want %q", lnk.LocalBasePath, `C:\test\a.txt`)
+	}
+	if got, err := lnk.ResolveTarget(); err != nil || got != `C:\test\a.txt` {
+		t.Errorf("ResolveTarget() = %q, %v", got, err)
+	}
+
+	// without the flag, an offset out of bounds is ignored
+	endianness.PutUint32(linkInfo[16:], 0xffff)
+	lnk, err = ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	if err != nil || lnk.LocalBasePath != "" {
+		t.Errorf("LocalBasePath = %q, %v with LocalBasePathOffset 0xffff, want none", lnk.LocalBasePath, err)
+	}
+}
@@ -2,22 +2,119 @@ package lnk
 
 import (
 	"encoding/binary"
+	"fmt"
 	"strconv"
 	"time"
 )
 
+// Values of LNK.ShowCommand, which are the SW_* values of ShowWindow. Only
+// ShowNormal, ShowMaximized and ShowMinNoActive are defined by MS-SHLLINK, and
+// the others are treated as ShowNormal when the shortcut is opened (see
+// EffectiveShowCommand), but they are found in files nonetheless.
 const (
+	// ShowHide is SW_HIDE.
+	ShowHide = 0
+
 	// ShowNormal is the value of LNK.ShowCommand when the application should be
 	// opened normally.
 	ShowNormal = 1
 
+	// ShowMinimized is SW_SHOWMINIMIZED, which activates the window and
+	// minimizes it.
+	ShowMinimized = 2
+
 	// ShowMaximized is the value of LNK.ShowCommand when the application should be
 	// opened maximized.
 	ShowMaximized = 3
 
+	// ShowNoActivate is SW_SHOWNOACTIVATE, which opens the window normally
+	// without activating it.
+	ShowNoActivate = 4
+
+	// ShowShow is SW_SHOW, which activates the window at its current size and
+	// position.
+	ShowShow = 5
+
+	// ShowMinimize is SW_MINIMIZE, which minimizes the window and activates
+	// the next one.
+	ShowMinimize = 6
+
 	// ShowMinNoActive is the value of LNK.ShowCommand when the application should
 	// be opened minimized.
 	ShowMinNoActive = 7
+
+	// ShowNA is SW_SHOWNA, which shows the window at its current size and
+	// position without activating it.
+	ShowNA = 8
+
+	// ShowRestore is SW_RESTORE.
+	ShowRestore = 9
+
+	// ShowDefault is SW_SHOWDEFAULT.
+	ShowDefault = 10
+
+	// ShowForceMinimize is SW_FORCEMINIMIZE.
+	ShowForceMinimize = 11
+)
+
+// ShowCommandString returns the name of ShowCommand. Values other than
+// ShowNormal, ShowMaximized and ShowMinNoActive are named as they are, even
+// though they are opened as ShowNormal.
+func (lnk *LNK) ShowCommandString() string {
+	switch lnk.ShowCommand {
+	case ShowHide:
+		return "hidden"
+	case ShowNormal:
+		return "normal"
+	case ShowMinimized:
+		return "minimized (active)"
+	case ShowMaximized:
+		return "maximized"
+	case ShowNoActivate:
+		return "normal (inactive)"
+	case ShowShow:
+		return "show"
+	case ShowMinimize:
+		return "minimize"
+	case ShowMinNoActive:
+		return "minimized"
+	case ShowNA:
+		return "show (inactive)"
+	case ShowRestore:
+		return "restore"
+	case ShowDefault:
+		return "default"
+	case ShowForceMinimize:
+		return "force minimize"
+	}
+	return fmt.Sprintf("unknown (%d)", lnk.ShowCommand)
+}
+
+// EffectiveShowCommand returns ShowCommand as it is applied when the shortcut
+// is opened: values other than ShowNormal, ShowMaximized and ShowMinNoActive
+// are treated as ShowNormal (MS-SHLLINK 2.1).
+func (lnk *LNK) EffectiveShowCommand() uint32 {
+	switch lnk.ShowCommand {
+	case ShowMaximized, ShowMinNoActive:
+		return lnk.ShowCommand
+	}
+	return ShowNormal
+}
+
+// Section is a set of the top-level structures of a .lnk file.
+type Section uint8
+
+const (
+	// SectionHeader is the ShellLinkHeader.
+	SectionHeader Section = 1 << iota
+	// SectionIDList is the LinkTargetIDList.
+	SectionIDList
+	// SectionLinkInfo is the LinkInfo.
+	SectionLinkInfo
+	// SectionStringData is the StringData.
+	SectionStringData
+	// SectionExtraData is the ExtraData.
+	SectionExtraData
 )
 
 // LNK represents the parsed information in a .lnk file.
@@ -25,8 +122,24 @@ const (
 //
 // https://msdn.microsoft.com/library/dd871305.aspx
 type LNK struct {
+	// Parsed is the set of sections that were read successfully, including
+	// optional sections that are absent. When parsing fails, it tells which
+	// of the fields can be relied upon.
+	Parsed Section
+	// EndOffset is the offset immediately after the TerminalBlock, i.e. the
+	// size of the shortcut, so that data appended to a file can be measured
+	// by comparing it with the size of the file. It is zero unless
+	// SectionExtraData is in Parsed.
+	EndOffset int64
+
 	// ShellLinkHeader (https://msdn.microsoft.com/library/dd891343.aspx)
+	// CLSID is the LinkCLSID as read from the file.
+	CLSID GUID
 	// LinkFlags (https://msdn.microsoft.com/library/dd891314.aspx)
+	// LinkFlags is the value as read from the file, including bits that have
+	// no boolean. Unused1 and Unused2 should be zero; their being set may
+	// indicate tampering.
+	LinkFlags                   uint32
 	HasLinkInfo                 bool
 	HasName                     bool
 	HasRelativePath             bool
@@ -37,10 +150,12 @@ type LNK struct {
 	ForceNoLinkInfo             bool
 	HasExpString                bool
 	RunInSeperateProcess        bool
+	Unused1                     bool
 	HasDarwinID                 bool
 	RunAsUser                   bool
 	HasExpIcon                  bool
 	NoPidlAlias                 bool
+	Unused2                     bool
 	RunWithShimLayer            bool
 	ForceNoLinkTrack            bool
 	EnableTargetMetadata        bool
@@ -52,6 +167,9 @@ type LNK struct {
 	PreferEnvironmentPath       bool
 	KeepLocalIDListForUNCTarget bool
 	// FileAttributes (https://msdn.microsoft.com/library/dd871338.aspx)
+	// FileAttributes is the value as read from the file, including bits that
+	// have no boolean.
+	FileAttributes    uint32
 	ReadOnly          bool
 	Hidden            bool
 	System            bool
@@ -70,23 +188,94 @@ type LNK struct {
 	AccessTime   time.Time
 	WriteTime    time.Time
 	FileSize     uint32
-	IconIndex    int32
-	ShowCommand  uint32
-	HotKey       HotKey
+	// IconIndex is the index of the icon in IconLocation, or, if negative,
+	// the negated resource ID of the icon. See IconResourceID.
+	IconIndex   int32
+	ShowCommand uint32
+	HotKey      HotKey
 
 	// LinkTargetIDList (https://msdn.microsoft.com/library/dd891268.aspx)
 	// IDList (https://msdn.microsoft.com/library/dd871365.aspx)
 	IDListBytes []byte
 
 	// LinkInfo (https://msdn.microsoft.com/library/dd871404.aspx)
+	LinkInfoSize                           uint32
 	VolumeIDAndLocalBasePath               bool
 	CommonNetworkRelativeLinkAndPathSuffix bool
 	// VolumeID (https://msdn.microsoft.com/library/dd891327.aspx)
 	DriveType         uint32
 	DriveSerialNumber uint32
-	VolumeLabel       string
+	// VolumeLabel is VolumeLabelANSI until PreferredStrings is called, unless
+	// the VolumeLabelOffset is 0x14, in which case the label is only stored
+	// in Unicode and VolumeLabel is always VolumeLabelUnicode.
+	VolumeLabel        string
+	VolumeLabelANSI    string
+	VolumeLabelUnicode string
+	// LinkInfo (https://msdn.microsoft.com/library/dd871404.aspx)
+	// LocalBasePath is LocalBasePathANSI until PreferredStrings is called.
+	// It is read even if VolumeIDAndLocalBasePath is clear, provided the
+	// LinkInfo locates it, but ResolveTarget then ignores it.
+	LocalBasePath        string
+	LocalBasePathANSI    string
+	LocalBasePathUnicode string
+	// CommonNetworkRelativeLink (MS-SHLLINK 2.3.2)
+	// ValidDevice and ValidNetType tell whether DeviceName and
+	// NetworkProviderType are set.
+	ValidDevice  bool
+	ValidNetType bool
+	// NetName and DeviceName are their ANSI variants until PreferredStrings
+	// is called.
+	NetName             string
+	NetNameANSI         string
+	NetNameUnicode      string
+	DeviceName          string
+	DeviceNameANSI      string
+	DeviceNameUnicode   string
+	NetworkProviderType uint32
 	// LinkInfo (https://msdn.microsoft.com/library/dd871404.aspx)
-	LocalBasePath string
+	// CommonPathSuffix is CommonPathSuffixANSI until PreferredStrings is
+	// called.
+	CommonPathSuffix        string
+	CommonPathSuffixANSI    string
+	CommonPathSuffixUnicode string
+
+	// StringData (MS-SHLLINK 2.4)
+	Name         string
+	RelativePath string
+	WorkingDir   string
+	Arguments    string
+	IconLocation string
+
+	// ExtraData (MS-SHLLINK 2.5)
+	EnvironmentVariable *EnvironmentVariableData
+	IconEnvironment     *EnvironmentVariableData
+	Console             *ConsoleData
+	Tracker             *TrackerData
+	Darwin              *DarwinData
+	// VistaAndAboveIDList is the IDList in the VistaAndAboveIDListDataBlock,
+	// which is used instead of the LinkTargetIDList by Windows Vista and
+	// later (MS-SHLLINK 2.5.11).
+	VistaAndAboveIDList []byte
+	SpecialFolder       *SpecialFolderData
+	KnownFolder         *KnownFolderData
+	PropertyStore       []Property
+	UnknownBlocks       []ExtraDataBlock
+	// DecodedBlocks holds the values returned by the decoders registered with
+	// RegisterBlockDecoder, keyed by signature.
+	DecodedBlocks map[uint32]interface{}
+
+	// Raw sections, only retained if ParseOptions.RetainRaw is set
+	RawHeader     []byte
+	RawIDList     []byte
+	RawLinkInfo   []byte
+	RawStringData []byte
+	RawExtraData  []byte
+
+	extraBlocks []uint32
+	warnings    []string
+	lazy        *lazyExtraData
+	location    *time.Location
+	decodeANSI  func([]byte) string
 }
 
 type HotKey struct {
@@ -96,6 +285,10 @@ type HotKey struct {
 	Shift bool
 	Ctrl  bool
 	Alt   bool
+	// Reserved holds the bits of the high byte other than those of Shift,
+	// Ctrl and Alt, which should be zero. Their being set may indicate
+	// tampering or a non-standard writer.
+	Reserved byte
 }
 
 func (hotKey HotKey) String() string {
@@ -126,10 +319,136 @@ func (hotKey HotKey) String() string {
 	return str
 }
 
+// Warnings returns descriptions of the irregularities that were tolerated
+// while parsing the shortcut, which ParseOptions.Strict makes errors instead.
+func (lnk *LNK) Warnings() []string {
+	return lnk.warnings
+}
+
+// warn records an irregularity at offset in section as a warning, or returns
+// it as an error wrapping err in strict mode.
+func (lnk *LNK) warn(strict bool, section Section, offset int64, msg string, err error) error {
+	if strict {
+		return &ParseError{
+			Section: section,
+			Offset:  offset,
+			Msg:     msg,
+			Err:     err,
+		}
+	}
+	lnk.warnings = append(lnk.warnings, msg)
+	return nil
+}
+
+// CLSIDString returns the LinkCLSID in registry form, e.g.
+// "{00021401-0000-0000-C000-000000000046}".
+func (lnk *LNK) CLSIDString() string {
+	return lnk.CLSID.String()
+}
+
+// Icon returns the file the icon is taken from and IconIndex. The path in the
+// IconEnvironmentDataBlock takes precedence when HasExpIcon is set, as it does
+// for the shell, and is returned without expanding its environment variables.
+// Otherwise, IconLocation is returned. ok is false if there is neither.
+func (lnk *LNK) Icon() (path string, index int, ok bool) {
+	lnk.LoadExtraData()
+	if lnk.HasExpIcon && lnk.IconEnvironment != nil && lnk.IconEnvironment.Target() != "" {
+		return lnk.IconEnvironment.Target(), int(lnk.IconIndex), true
+	}
+	if lnk.HasIconLocation {
+		return lnk.IconLocation, int(lnk.IconIndex), true
+	}
+	return "", 0, false
+}
+
+// IconResourceID interprets IconIndex as ExtractIcon does: a non-negative
+// value is the zero-based index of the icon in IconLocation, while a negative
+// value is the negated resource ID of the icon. isResourceID reports which
+// one id is.
+func (lnk *LNK) IconResourceID() (id int, isResourceID bool) {
+	if lnk.IconIndex < 0 {
+		return -int(lnk.IconIndex), true
+	}
+	return int(lnk.IconIndex), false
+}
+
+// RunsElevated reports whether the shortcut asks for the target to be run as
+// an administrator, which is the "Run as administrator" checkbox in its
+// advanced properties. It is the RunAsUser LinkFlag, bit 13 (0x00002000).
+func (lnk *LNK) RunsElevated() bool {
+	return lnk.RunAsUser
+}
+
+// modifier flags of RegisterHotKey
+const (
+	modAlt     = 0x1
+	modControl = 0x2
+	modShift   = 0x4
+)
+
+// HotKeyModifiers returns the hotkey in the form RegisterHotKey expects: a
+// bitmask of MOD_ALT, MOD_CONTROL and MOD_SHIFT, and the virtual-key code. Both
+// are zero if no hotkey is assigned.
+func (lnk *LNK) HotKeyModifiers() (mod uint32, vk uint32) {
+	if lnk.HotKey.Key == 0 {
+		return 0, 0
+	}
+
+	if lnk.HotKey.Alt {
+		mod |= modAlt
+	}
+	if lnk.HotKey.Ctrl {
+		mod |= modControl
+	}
+	if lnk.HotKey.Shift {
+		mod |= modShift
+	}
+	return mod, uint32(lnk.HotKey.Key)
+}
+
+// HotKeyAccelerator returns the hotkey as the event and options of an entry
+// in an ACCELERATORS resource script, e.g. `VK_F4, VIRTKEY, CONTROL, ALT` for
+// Ctrl+Alt+F4. The command identifier of the entry goes after the event, as in
+// `VK_F4, IDM_RUN, VIRTKEY, CONTROL, ALT`. Letters and digits are quoted, as in
+// `"A"`, and keys that have no name are numbered. It returns an empty string
+// if no hotkey is assigned.
+func (lnk *LNK) HotKeyAccelerator() string {
+	key := lnk.HotKey.Key
+	if key == 0 {
+		return ""
+	}
+
+	var event string
+	switch {
+	case key >= '0' && key <= '9' || key >= 'A' && key <= 'Z':
+		event = `"` + string(key) + `"`
+	case key >= 0x70 && key <= 0x87:
+		event = "VK_F" + strconv.Itoa(int(key-0x6f))
+	case key == 0x90:
+		event = "VK_NUMLOCK"
+	case key == 0x91:
+		event = "VK_SCROLL"
+	default:
+		event = fmt.Sprintf("0x%02X", key)
+	}
+
+	accelerator := event + ", VIRTKEY"
+	if lnk.HotKey.Shift {
+		accelerator += ", SHIFT"
+	}
+	if lnk.HotKey.Ctrl {
+		accelerator += ", CONTROL"
+	}
+	if lnk.HotKey.Alt {
+		accelerator += ", ALT"
+	}
+	return accelerator
+}
+
 var endianness = binary.LittleEndian
 
 // 00021401-0000-0000-C000-000000000046
-var validCLSID = [16]byte{
+var validCLSID = GUID{
 	0x01, 0x14, 0x02, 0x00,
 	0x00, 0x00,
 	0x00, 0x00,
@@ -144,6 +463,20 @@ func windowsNanoToTime(windowsNano uint64) time.Time {
 	// fmt.Println(time.Unix(0, 100*windowsNano-11644473600000000000))
 	// fmt.Println(time.Unix(0, 100*(windowsNano-116444736000000000)))
 
+	// zero means the time is not set
+	if windowsNano == 0 {
+		return time.Time{}
+	}
+
 	// this converts the Windows nanoseconds to Unix nanoseconds
-	return time.Unix(0, int64(100*windowsNano-11644473600000000000))
+	return time.Unix(0, int64(100*windowsNano-11644473600000000000)).UTC()
+}
+
+// inLocation returns t in the location set by ParseOptions.Location. The zero
+// time is returned as-is.
+func (lnk *LNK) inLocation(t time.Time) time.Time {
+	if lnk.location == nil || t.IsZero() {
+		return t
+	}
+	return t.In(lnk.location)
 }
@@ -0,0 +1,168 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+// readTestdata returns the contents of a file in testdata.
+func readTestdata(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// load parses a shortcut in testdata.
+func load(t testing.TB, name string) *LNK {
+	t.Helper()
+	lnk, err := Parse(bytes.NewReader(readTestdata(t, name)))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return lnk
+}
+
+func TestShowCommand(t *testing.T) {
+	tests := []struct {
+		showCommand uint32
+		name        string
+		effective   uint32
+	}{
+		{ShowHide, "hidden", ShowNormal},
+		{ShowNormal, "normal", ShowNormal},
+		{ShowMinimized, "minimized (active)", ShowNormal},
+		{ShowMaximized, "maximized", ShowMaximized},
+		{ShowNoActivate, "normal (inactive)", ShowNormal},
+		{ShowShow, "show", ShowNormal},
+		{ShowMinimize, "minimize", ShowNormal},
+		{ShowMinNoActive, "minimized", ShowMinNoActive},
+		{ShowNA, "show (inactive)", ShowNormal},
+		{ShowRestore, "restore", ShowNormal},
+		{ShowDefault, "default", ShowNormal},
+		{ShowForceMinimize, "force minimize", ShowNormal},
+		{12, "unknown (12)", ShowNormal},
+	}
+	for _, test := range tests {
+		lnk := &LNK{ShowCommand: test.showCommand}
+		if got := lnk.ShowCommandString(); got != test.name {
+			t.Errorf("ShowCommandString() for %d = %q, want %q", test.showCommand, got, test.name)
+		}
+		if got := lnk.EffectiveShowCommand(); got != test.effective {
+			t.Errorf("EffectiveShowCommand() for %d = %d, want %d", test.showCommand, got, test.effective)
+		}
+	}
+}
+
+func TestHotKeyModifiers(t *testing.T) {
+	tests := []struct {
+		hotKey HotKey
+		mod    uint32
+		vk     uint32
+	}{
+		// Ctrl+Alt+F2
+		{HotKey{Key: 0x71, Ctrl: true, Alt: true}, 0x1 | 0x2, 0x71},
+		// Shift+A
+		{HotKey{Key: 0x41, Shift: true}, 0x4, 0x41},
+		// no hotkey, whatever the modifiers
+		{HotKey{Ctrl: true}, 0, 0},
+	}
+	for _, test := range tests {
+		lnk := &LNK{HotKey: test.hotKey}
+		if mod, vk := lnk.HotKeyModifiers(); mod != test.mod || vk != test.vk {
+			t.Errorf("HotKeyModifiers() for %+v = 0x%x, 0x%x, want 0x%x, 0x%x", test.hotKey, mod, vk, test.mod, test.vk)
+		}
+	}
+}
+
+func TestHotKeyAccelerator(t *testing.T) {
+	tests := []struct {
+		hotKey HotKey
+		want   string
+	}{
+		// Ctrl+Alt+F4
+		{HotKey{Key: 0x73, Ctrl: true, Alt: true}, "VK_F4, VIRTKEY, CONTROL, ALT"},
+		{HotKey{Key: 'A', Shift: true}, `"A", VIRTKEY, SHIFT`},
+		{HotKey{Key: '7', Ctrl: true, Shift: true}, `"7", VIRTKEY, SHIFT, CONTROL`},
+		{HotKey{Key: 0x87, Alt: true}, "VK_F24, VIRTKEY, ALT"},
+		{HotKey{Key: 0x90, Ctrl: true}, "VK_NUMLOCK, VIRTKEY, CONTROL"},
+		{HotKey{Key: 0x2d, Ctrl: true}, "0x2D, VIRTKEY, CONTROL"},
+		{HotKey{Ctrl: true, Alt: true}, ""},
+	}
+	for _, test := range tests {
+		lnk := &LNK{HotKey: test.hotKey}
+		if got := lnk.HotKeyAccelerator(); got != test.want {
+			t.Errorf("HotKeyAccelerator() for %+v = %q, want %q", test.hotKey, got, test.want)
+		}
+	}
+}
+
+func TestIconResourceID(t *testing.T) {
+	tests := []struct {
+		iconIndex    int32
+		id           int
+		isResourceID bool
+	}{
+		{0, 0, false},
+		{3, 3, false},
+		{-101, 101, true},
+	}
+	for _, test := range tests {
+		lnk := &LNK{IconIndex: test.iconIndex}
+		if id, isResourceID := lnk.IconResourceID(); id != test.id || isResourceID != test.isResourceID {
+			t.Errorf("IconResourceID() for %d = %d, %v, want %d, %v", test.iconIndex, id, isResourceID, test.id, test.isResourceID)
+		}
+	}
+}
+
+func TestRunsElevated(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	if load(t, "local.lnk").RunsElevated() {
+		t.Error("RunsElevated() = true without RunAsUser")
+	}
+
+	// the "Run as administrator" checkbox
+	elevated := append([]byte(nil), data...)
+	endianness.PutUint32(elevated[20:], endianness.Uint32(elevated[20:])|0x2000)
+	lnk, err := Parse(bytes.NewReader(elevated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lnk.RunsElevated() || !lnk.RunAsUser {
+		t.Errorf("RunsElevated() = %v, RunAsUser = %v, want true", lnk.RunsElevated(), lnk.RunAsUser)
+	}
+}
+
+func TestHotKeyReservedBits(t *testing.T) {
+	data := append([]byte(nil), readTestdata(t, "local.lnk")...)
+	// the high byte of the HotKey
+	data[65] = 0xff
+
+	lnk, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.HotKey.Reserved != 0xf8 || !lnk.HotKey.Shift || !lnk.HotKey.Ctrl || !lnk.HotKey.Alt {
+		t.Errorf("HotKey = %+v, want reserved bits 0xf8 and every modifier", lnk.HotKey)
+	}
+	if len(lnk.Warnings()) != 1 {
+		t.Errorf("Warnings() = %q, want one warning", lnk.Warnings())
+	}
+	written, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written[65] != 0xff {
+		t.Errorf("high byte written as 0x%x, want 0xff", written[65])
+	}
+
+	_, err = ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	var parseErr *ParseError
+	if !errors.Is(err, ErrReservedBitSet) || !errors.As(err, &parseErr) || parseErr.Offset != 65 {
+		t.Errorf("strict: error = %v, want %v at offset 65", err, ErrReservedBitSet)
+	}
+}
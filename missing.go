@@ -0,0 +1,41 @@
+package lnk
+
+// MissingExpectedData cross-references the LinkFlags and LinkInfoFlags with
+// the data that was parsed, and describes each structure that a flag says is
+// present but that is missing or empty, such as "HasArguments is set but
+// Arguments is empty". Such inconsistencies indicate that the shortcut is
+// corrupt or that it was only partially parsed.
+func (lnk *LNK) MissingExpectedData() []string {
+	lnk.LoadExtraData()
+
+	var missing []string
+	check := func(flag bool, flagName string, present bool, data string) {
+		if flag && !present {
+			missing = append(missing, flagName+" is set but "+data)
+		}
+	}
+
+	check(lnk.LinkFlags&(1<<0) != 0, "HasLinkTargetIDList", len(lnk.IDListBytes) != 0, "the IDList is empty")
+	check(lnk.HasLinkInfo, "HasLinkInfo", lnk.LinkInfoSize != 0, "LinkInfoSize is 0")
+	if lnk.HasLinkInfo && lnk.LinkInfoSize != 0 {
+		check(lnk.VolumeIDAndLocalBasePath, "VolumeIDAndLocalBasePath", lnk.LocalBasePath != "", "LocalBasePath is empty")
+		check(lnk.CommonNetworkRelativeLinkAndPathSuffix, "CommonNetworkRelativeLinkAndPathSuffix", lnk.NetName != "", "NetName is empty")
+	}
+
+	check(lnk.HasName, "HasName", lnk.Name != "", "Name is empty")
+	check(lnk.HasRelativePath, "HasRelativePath", lnk.RelativePath != "", "RelativePath is empty")
+	check(lnk.HasWorkingDir, "HasWorkingDir", lnk.WorkingDir != "", "WorkingDir is empty")
+	check(lnk.HasArguments, "HasArguments", lnk.Arguments != "", "Arguments is empty")
+	check(lnk.HasIconLocation, "HasIconLocation", lnk.IconLocation != "", "IconLocation is empty")
+
+	blocks := make(map[uint32]bool)
+	for _, signature := range lnk.extraBlocks {
+		blocks[signature] = true
+	}
+	check(lnk.HasExpString, "HasExpString", lnk.EnvironmentVariable != nil, "there is no EnvironmentVariableDataBlock")
+	check(lnk.HasExpIcon, "HasExpIcon", lnk.IconEnvironment != nil, "there is no IconEnvironmentDataBlock")
+	check(lnk.HasDarwinID, "HasDarwinID", lnk.Darwin != nil, "there is no DarwinDataBlock")
+	check(lnk.RunWithShimLayer, "RunWithShimLayer", blocks[ShimDataBlockSignature], "there is no ShimDataBlock")
+
+	return missing
+}
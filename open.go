@@ -2,9 +2,14 @@ package lnk
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"errors"
-	"strings"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"time"
 )
 
 var (
@@ -22,36 +27,302 @@ var (
 
 	// ErrInvalidSize is returned when a field has an invalid size
 	ErrInvalidSize = errors.New("invalid field size")
+
+	// ErrTrailingData is returned in strict mode when a string is followed by
+	// data other than NULs after its terminating NUL
+	ErrTrailingData = errors.New("data after string terminator")
+
+	// ErrNoTerminalID is returned in strict mode when the IDList does not end
+	// with a TerminalID
+	ErrNoTerminalID = errors.New("IDList has no TerminalID")
+
+	// ErrInvalidPropertyStore is returned when a serialized property storage
+	// has an invalid version
+	ErrInvalidPropertyStore = errors.New("invalid property store")
+
+	// ErrTooLarge is returned when the LinkInfo or an ExtraData block is
+	// larger than ParseOptions.MaxStructureSize
+	ErrTooLarge = errors.New("structure too large")
 )
 
-// Open parses an io.Reader into a LNK.
+// ParseError describes a malformed shortcut in more detail than the error it
+// wraps.
+type ParseError struct {
+	// Section is the section that is malformed.
+	Section Section
+	// Offset is the offset from the start of the shortcut of the malformed
+	// field or structure.
+	Offset int64
+	Msg    string
+	// Err is the underlying error, such as io.ErrUnexpectedEOF.
+	Err error
+}
+
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("offset %d: %s", err.Offset, err.Msg)
+}
+
+// Unwrap returns the underlying error.
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// malformed returns a ParseError for a field at offset whose value cannot be
+// parsed, wrapping err.
+func malformed(section Section, offset int64, msg string, err error) error {
+	return &ParseError{
+		Section: section,
+		Offset:  offset,
+		Msg:     msg,
+		Err:     err,
+	}
+}
+
+// Open parses a bufio.Reader into a LNK.
 func Open(file *bufio.Reader) (*LNK, error) {
+	return Parse(file)
+}
+
+// ParseFS parses the named shortcut in fsys, such as an embed.FS. The file is
+// closed before ParseFS returns.
+func ParseFS(fsys fs.FS, name string) (*LNK, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// ParseOptions controls how a shortcut is parsed.
+type ParseOptions struct {
+	// RetainRaw stores the exact bytes of each section in the Raw fields of
+	// the LNK, which roughly doubles memory use.
+	RetainRaw bool
+
+	// SkipExtraData stops parsing after StringData, which is considerably
+	// faster when only the target and timestamps are needed. ExtraData is
+	// left unread, so SectionExtraData is not set in Parsed.
+	SkipExtraData bool
+
+	// Lazy reads ExtraData without decoding it, which is deferred until
+	// LoadExtraData is called, or a method that needs ExtraData is. Until
+	// then, the ExtraData fields of the LNK are empty.
+	Lazy bool
+
+	// Strict turns the irregularities that are otherwise recorded as warnings
+	// (see LNK.Warnings), such as a missing TerminalBlock, into errors.
+	Strict bool
+
+	// AllowForeignCLSID accepts a LinkCLSID other than the one MS-SHLLINK
+	// requires, as written by some buggy tools, for instance with its fields
+	// in the wrong byte order. It is kept in LNK.CLSID and recorded as a
+	// warning, even if Strict is set, rather than making parsing fail with
+	// ErrInvalidCLSID. WriteTo still writes the required CLSID.
+	AllowForeignCLSID bool
+
+	// Progress, if not nil, is called as the IDList is read, which can take
+	// a while for large IDLists read from slow media, with the number of
+	// bytes read so far and the total, which is the IDListSize. It is called
+	// after each chunk of 4096 bytes and once the IDList is read, which may
+	// be with fewer bytes than the total if it is truncated.
+	Progress func(bytesRead, total int64)
+
+	// BufferSize is the size of the buffer the shortcut is read through, which
+	// is 4096 bytes if it is not positive. A larger buffer reduces the reads
+	// from r for shortcuts with large IDLists or property stores, such as
+	// those in forensic images.
+	BufferSize int
+
+	// MaxStructureSize is the size in bytes of the largest LinkInfo or
+	// ExtraData block that is read, which is 16 MiB if it is not positive.
+	// Their sizes are 32-bit, so without a limit a shortcut could make the
+	// parser read up to 4 GiB into memory for each of them, for instance from
+	// a small compressed file in a zip archive. Larger structures make
+	// parsing fail with ErrTooLarge.
+	MaxStructureSize int
+
+	// Logger, if not nil, receives a debug record for each section that is
+	// parsed, holding its offset from the start of the shortcut and its size.
+	Logger *slog.Logger
+
+	// Location, if not nil, is the location timestamps are returned in, such
+	// as time.Local. Timestamps are stored in UTC, so they are returned in UTC
+	// by default; they used to be returned in the local time zone.
+	Location *time.Location
+
+	// DecodeANSI, if not nil, decodes strings in the code page of the system
+	// that created the shortcut, such as the 8.3 names of ItemIDs, which are
+	// otherwise returned as-is. A decoder from golang.org/x/text/encoding
+	// can be adapted to it.
+	DecodeANSI func([]byte) string
+
+	// targetOnly stops parsing after the LinkInfo, and discards the IDList,
+	// for QuickTarget.
+	targetOnly bool
+}
+
+// Parse parses an io.Reader into a LNK. Malformed input results in an error,
+// never a panic. Sizes declared by the input are not trusted for allocations:
+// memory is allocated as data arrives, and structures larger than
+// ParseOptions.MaxStructureSize are rejected.
+func Parse(r io.Reader) (*LNK, error) {
+	return ParseWithOptions(r, ParseOptions{})
+}
+
+// ParseWithOptions parses an io.Reader into a LNK using opts.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (*LNK, error) {
+	return parse(&countingReader{r: newReader(r, opts.BufferSize)}, opts)
+}
+
+// newReader buffers r with a buffer of size bytes, or of the default size if
+// size is not positive.
+func newReader(r io.Reader, size int) *bufio.Reader {
+	if size <= 0 {
+		return bufio.NewReader(r)
+	}
+	return bufio.NewReaderSize(r, size)
+}
+
+// QuickTarget returns the path of the target from the LinkInfo, as
+// ResolveTarget does, reading only the header and the LinkInfo, and
+// discarding the IDList. It is considerably faster than Parse for scanning
+// many shortcuts, and does not read from r past the LinkInfo. ErrNoTarget is returned if the shortcut has no LinkInfo
+// or it is ignored, as for shortcuts whose target is only in the IDList, such
+// as packaged applications; ResolveTarget handles those.
+func QuickTarget(r io.Reader) (string, error) {
+	lnk, err := parse(&countingReader{r: r}, ParseOptions{targetOnly: true})
+	if err != nil {
+		return "", err
+	}
+	if !lnk.HasLinkInfo || lnk.LinkInfoIgnored() {
+		return "", ErrNoTarget
+	}
+	if lnk.VolumeIDAndLocalBasePath && lnk.localBasePath() != "" {
+		return lnk.localBasePath() + lnk.commonPathSuffix(), nil
+	}
+	if path := lnk.UNCPath(); path != "" {
+		return path, nil
+	}
+	return "", ErrNoTarget
+}
+
+// Parser parses shortcuts one after another, reusing its buffer between them
+// to reduce allocations. Each returned LNK is independent of the others. A
+// Parser must not be used concurrently. The zero value is ready to use.
+type Parser struct {
+	// Options is used for every shortcut.
+	Options ParseOptions
+
+	buf *bufio.Reader
+}
+
+// Parse parses an io.Reader into a LNK, as ParseWithOptions does.
+func (p *Parser) Parse(r io.Reader) (*LNK, error) {
+	if p.buf == nil || p.Options.BufferSize > 0 && p.buf.Size() != max(p.Options.BufferSize, 16) {
+		p.buf = newReader(r, p.Options.BufferSize)
+	} else {
+		p.buf.Reset(r)
+	}
+	lnk, err := parse(&countingReader{r: p.buf}, p.Options)
+	// the reader is not retained past the call
+	p.buf.Reset(nil)
+	return lnk, err
+}
+
+// ParseN is like Parse, but also returns the number of bytes of r that make up
+// the shortcut, which is useful when it is embedded in a larger stream. The
+// count excludes anything buffered past the end of the shortcut.
+func ParseN(r io.Reader) (*LNK, int64, error) {
+	file := &countingReader{r: bufio.NewReader(r)}
+	lnk, err := parse(file, ParseOptions{})
+	return lnk, file.n, err
+}
+
+// ParseWithHeader parses a shortcut whose first four bytes, the HeaderSize,
+// have already been read from rest, e.g. by a content sniffer. The header
+// size must still be 76.
+func ParseWithHeader(headerSizeBytes [4]byte, rest io.Reader) (*LNK, error) {
+	return Parse(io.MultiReader(bytes.NewReader(headerSizeBytes[:]), rest))
+}
+
+// ParseAll parses shortcuts that are stored back to back, as in jump lists
+// and some other artifacts, until the end of r. Each shortcut starts
+// immediately after the TerminalBlock of the previous one. If parsing fails,
+// the shortcuts parsed so far are returned along with the error.
+func ParseAll(r io.Reader) ([]*LNK, error) {
+	file := bufio.NewReader(r)
+	var lnks []*LNK
+	for {
+		_, err := file.Peek(1)
+		if err == io.EOF {
+			return lnks, nil
+		}
+		if err != nil {
+			return lnks, err
+		}
+
+		lnk, err := parse(&countingReader{r: file}, ParseOptions{})
+		if err != nil {
+			return lnks, err
+		}
+		lnks = append(lnks, lnk)
+	}
+}
+
+// parse reads exactly one shortcut from file, so that anything following it
+// is left unread. io.EOF is only returned if file is empty, and a shortcut
+// that ends early fails with io.ErrUnexpectedEOF.
+func parse(file *countingReader, opts ParseOptions) (*LNK, error) {
+	lnk, err := parseSections(file, opts)
+	if err == io.EOF && file.n != 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return lnk, err
+}
+
+// parseSections implements parse.
+func parseSections(file *countingReader, opts ParseOptions) (*LNK, error) {
+	file.record = opts.RetainRaw
 	lnk := new(LNK)
+	lnk.decodeANSI = opts.DecodeANSI
+	lnk.location = opts.Location
+
+	var start int64
+	logSection := func(name string) {
+		if opts.Logger != nil {
+			opts.Logger.Debug("parsed section", "section", name, "offset", start, "size", file.n-start)
+		}
+		start = file.n
+	}
 
 	// ShellLinkHeader
-	var headerSize uint32
-	err := binary.Read(file, endianness, &headerSize)
+	// the header is read at once and decoded by hand, which is considerably
+	// faster than reading it field by field
+	var header [76]byte
+	_, err := io.ReadFull(file, header[:4])
 	if err != nil {
 		return lnk, err
 	}
-	if headerSize != 76 {
+	if endianness.Uint32(header[:]) != 76 {
 		return lnk, ErrNotALink
 	}
-
-	var clsid [16]byte
-	_, err = file.Read(clsid[:])
+	_, err = io.ReadFull(file, header[4:])
 	if err != nil {
 		return lnk, err
 	}
-	if clsid != validCLSID {
-		return lnk, ErrInvalidCLSID
-	}
 
-	var linkFlags uint32
-	err = binary.Read(file, endianness, &linkFlags)
-	if err != nil {
-		return lnk, err
+	copy(lnk.CLSID[:], header[4:20])
+	if lnk.CLSID != validCLSID {
+		if !opts.AllowForeignCLSID {
+			return lnk, fmt.Errorf("%w: %s", ErrInvalidCLSID, lnk.CLSIDString())
+		}
+		lnk.warn(false, SectionHeader, 4, "unexpected CLSID "+lnk.CLSIDString(), ErrInvalidCLSID)
 	}
+
+	linkFlags := endianness.Uint32(header[20:])
+	lnk.LinkFlags = linkFlags
 	hasTargetIDList := linkFlags&(1<<0) != 0
 	lnk.HasLinkInfo = linkFlags&(1<<1) != 0
 	lnk.HasName = linkFlags&(1<<2) != 0
@@ -63,12 +334,12 @@ func Open(file *bufio.Reader) (*LNK, error) {
 	lnk.ForceNoLinkInfo = linkFlags&(1<<8) != 0
 	lnk.HasExpString = linkFlags&(1<<9) != 0
 	lnk.RunInSeperateProcess = linkFlags&(1<<10) != 0
-	// Unused1
+	lnk.Unused1 = linkFlags&(1<<11) != 0
 	lnk.HasDarwinID = linkFlags&(1<<12) != 0
 	lnk.RunAsUser = linkFlags&(1<<13) != 0
 	lnk.HasExpIcon = linkFlags&(1<<14) != 0
 	lnk.NoPidlAlias = linkFlags&(1<<15) != 0
-	// Unused2
+	lnk.Unused2 = linkFlags&(1<<16) != 0
 	lnk.RunWithShimLayer = linkFlags&(1<<17) != 0
 	lnk.ForceNoLinkTrack = linkFlags&(1<<18) != 0
 	lnk.EnableTargetMetadata = linkFlags&(1<<19) != 0
@@ -80,11 +351,8 @@ func Open(file *bufio.Reader) (*LNK, error) {
 	lnk.PreferEnvironmentPath = linkFlags&(1<<25) != 0
 	lnk.KeepLocalIDListForUNCTarget = linkFlags&(1<<26) != 0
 
-	var fileAttributes uint32
-	err = binary.Read(file, endianness, &fileAttributes)
-	if err != nil {
-		return lnk, err
-	}
+	fileAttributes := endianness.Uint32(header[24:])
+	lnk.FileAttributes = fileAttributes
 	lnk.ReadOnly = fileAttributes&(1<<0) != 0
 	lnk.Hidden = fileAttributes&(1<<1) != 0
 	lnk.System = fileAttributes&(1<<2) != 0
@@ -101,83 +369,42 @@ func Open(file *bufio.Reader) (*LNK, error) {
 	lnk.NotContentIndexed = fileAttributes&(1<<13) != 0
 	lnk.Encrypted = fileAttributes&(1<<14) != 0
 	if fileAttributes&(1<<3) != 0 || fileAttributes&(1<<6) != 0 {
-		return lnk, ErrReservedBitSet
+		return lnk, malformed(SectionHeader, 24, fmt.Sprintf("reserved FileAttributes bits are set: 0x%08x", fileAttributes&0x48), ErrReservedBitSet)
 	}
 
-	var creationTime uint64
-	err = binary.Read(file, endianness, &creationTime)
-	if err != nil {
-		return lnk, err
-	}
-	lnk.CreationTime = windowsNanoToTime(creationTime)
-
-	var accessTime uint64
-	err = binary.Read(file, endianness, &accessTime)
-	if err != nil {
-		return lnk, err
-	}
-	lnk.AccessTime = windowsNanoToTime(accessTime)
+	lnk.CreationTime = lnk.inLocation(windowsNanoToTime(endianness.Uint64(header[28:])))
+	lnk.AccessTime = lnk.inLocation(windowsNanoToTime(endianness.Uint64(header[36:])))
+	lnk.WriteTime = lnk.inLocation(windowsNanoToTime(endianness.Uint64(header[44:])))
+	lnk.FileSize = endianness.Uint32(header[52:])
+	lnk.IconIndex = int32(endianness.Uint32(header[56:]))
+	lnk.ShowCommand = endianness.Uint32(header[60:])
 
-	var writeTime uint64
-	err = binary.Read(file, endianness, &writeTime)
-	if err != nil {
-		return lnk, err
-	}
-	lnk.WriteTime = windowsNanoToTime(writeTime)
-
-	err = binary.Read(file, endianness, &lnk.FileSize)
-	if err != nil {
-		return lnk, err
-	}
-
-	err = binary.Read(file, endianness, &lnk.IconIndex)
-	if err != nil {
-		return lnk, err
-	}
-
-	err = binary.Read(file, endianness, &lnk.ShowCommand)
-	if err != nil {
-		return lnk, err
-	}
-
-	err = binary.Read(file, endianness, &lnk.HotKey.Key)
-	if err != nil {
-		return lnk, err
-	}
+	lnk.HotKey.Key = header[64]
 	if (lnk.HotKey.Key > 0x00 && lnk.HotKey.Key < 0x30) || (lnk.HotKey.Key > 0x39 && lnk.HotKey.Key < 0x41) || (lnk.HotKey.Key > 0x5a && lnk.HotKey.Key < 0x70) || (lnk.HotKey.Key > 0x87 && lnk.HotKey.Key < 0x90) || lnk.HotKey.Key > 0x91 {
-		return lnk, ErrInvalidHotKey
+		return lnk, malformed(SectionHeader, 64, fmt.Sprintf("invalid HotKey key 0x%02x", lnk.HotKey.Key), ErrInvalidHotKey)
 	}
 
-	var highByte byte
-	err = binary.Read(file, endianness, &highByte)
-	if err != nil {
-		return lnk, err
-	}
+	highByte := header[65]
 	lnk.HotKey.Shift = highByte&(1<<0) != 0
 	lnk.HotKey.Ctrl = highByte&(1<<1) != 0
 	lnk.HotKey.Alt = highByte&(1<<2) != 0
-
-	var reserved1 uint16
-	err = binary.Read(file, endianness, &reserved1)
-	if err != nil {
-		return lnk, err
-	}
-
-	var reserved2 uint32
-	err = binary.Read(file, endianness, &reserved2)
-	if err != nil {
-		return lnk, err
-	}
-
-	var reserved3 uint32
-	err = binary.Read(file, endianness, &reserved3)
-	if err != nil {
-		return lnk, err
+	lnk.HotKey.Reserved = highByte &^ 0x07
+	if lnk.HotKey.Reserved != 0 {
+		err = lnk.warn(opts.Strict, SectionHeader, 65, fmt.Sprintf("reserved HotKey bits are set: 0x%02x", lnk.HotKey.Reserved), ErrReservedBitSet)
+		if err != nil {
+			return lnk, err
+		}
 	}
 
+	reserved1 := endianness.Uint16(header[66:])
+	reserved2 := endianness.Uint32(header[68:])
+	reserved3 := endianness.Uint32(header[72:])
 	if reserved1 != 0 || reserved2 != 0 || reserved3 != 0 {
-		return lnk, ErrReservedBitSet
+		return lnk, malformed(SectionHeader, 66, "reserved header fields are set", ErrReservedBitSet)
 	}
+	lnk.Parsed |= SectionHeader
+	logSection("ShellLinkHeader")
+	lnk.RawHeader = file.take()
 
 	// LinkTargetIDList
 	if hasTargetIDList {
@@ -186,122 +413,335 @@ func Open(file *bufio.Reader) (*LNK, error) {
 		if err != nil {
 			return lnk, err
 		}
-		lnk.IDListBytes = make([]byte, idListSize)
-		_, err = file.Read(lnk.IDListBytes)
-		if err != nil {
-			return lnk, err
+		// IDListSize cannot exceed 0xffff, so the size is validated by its
+		// type, and only what is present is allocated
+		var n int64
+		if opts.targetOnly {
+			n, err = io.CopyN(io.Discard, file, int64(idListSize))
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+		} else {
+			if opts.Progress != nil {
+				lnk.IDListBytes, err = readBytesProgress(file, int64(idListSize), opts.Progress)
+			} else {
+				lnk.IDListBytes, err = readBytes(file, int64(idListSize))
+			}
+			n = int64(len(lnk.IDListBytes))
 		}
-	}
-
-	// LinkInfo
-	if lnk.HasLinkInfo && !lnk.ForceNoLinkInfo {
-		// LinkInfoSize
-		_, err = file.Discard(4)
-		if err != nil {
-			return lnk, err
+		if err == io.ErrUnexpectedEOF {
+			return lnk, &ParseError{
+				Section: SectionIDList,
+				Offset:  start,
+				Msg:     fmt.Sprintf("IDList declares %d bytes but only %d remain", idListSize, n),
+				Err:     err,
+			}
 		}
-
-		var linkInfoHeaderSize uint32
-		err = binary.Read(file, endianness, &linkInfoHeaderSize)
 		if err != nil {
 			return lnk, err
 		}
 
-		var linkInfoFlags uint32
-		err = binary.Read(file, endianness, &linkInfoFlags)
-		if err != nil {
-			return lnk, err
+		// the list ends with a TerminalID, a zero ItemIDSize, so a nonzero
+		// end indicates that it is truncated or crafted
+		if !opts.targetOnly && (idListSize < 2 || lnk.IDListBytes[idListSize-2] != 0 || lnk.IDListBytes[idListSize-1] != 0) {
+			err = lnk.warn(opts.Strict, SectionIDList, start+int64(idListSize), "IDList does not end with a TerminalID", ErrNoTerminalID)
+			if err != nil {
+				return lnk, err
+			}
 		}
-		lnk.VolumeIDAndLocalBasePath = linkInfoFlags&(1<<0) != 0
-		lnk.CommonNetworkRelativeLinkAndPathSuffix = linkInfoFlags&(1<<1) != 0
+	}
+	lnk.Parsed |= SectionIDList
+	logSection("LinkTargetIDList")
+	lnk.RawIDList = file.take()
 
-		// VolumeIDOffset
-		_, err = file.Discard(4)
+	// LinkInfo
+	// it is read even if ForceNoLinkInfo is set, as the structure is still
+	// present; LinkInfoIgnored tells whether it should be used
+	if lnk.HasLinkInfo {
+		err = binary.Read(file, endianness, &lnk.LinkInfoSize)
 		if err != nil {
 			return lnk, err
 		}
-
-		// LocalBasePathOffset
-		_, err = file.Discard(4)
-		if err != nil {
-			return lnk, err
+		if lnk.LinkInfoSize < 0x1c {
+			return lnk, malformed(SectionLinkInfo, start, fmt.Sprintf("LinkInfoSize 0x%x is smaller than the LinkInfo header", lnk.LinkInfoSize), ErrInvalidSize)
 		}
-
-		// CommonNetworkRelativeLinkOffset
-		_, err = file.Discard(4)
-		if err != nil {
+		if err := opts.checkSize(SectionLinkInfo, start, "LinkInfoSize", lnk.LinkInfoSize); err != nil {
 			return lnk, err
 		}
 
-		// CommonPathSuffixOffset
-		_, err = file.Discard(4)
+		// the whole structure is buffered so that StringData always starts
+		// immediately after it, regardless of how much of it is understood,
+		// and its fields are located by their offsets rather than assumed to
+		// follow the header
+		var linkInfo []byte
+		linkInfo, err = readBytes(file, int64(lnk.LinkInfoSize)-4)
 		if err != nil {
 			return lnk, err
 		}
+		linkInfo = append(make([]byte, 4), linkInfo...)
+		endianness.PutUint32(linkInfo, lnk.LinkInfoSize)
 
-		if linkInfoHeaderSize > 28 {
-			// LocalBasePathOffsetUnicode
-			_, err = file.Discard(4)
-			if err != nil {
-				return lnk, err
-			}
+		linkInfoHeaderSize := endianness.Uint32(linkInfo[4:])
+		if linkInfoHeaderSize < 0x1c || linkInfoHeaderSize > lnk.LinkInfoSize {
+			return lnk, malformed(SectionLinkInfo, start+4, fmt.Sprintf("LinkInfoHeaderSize 0x%x is invalid for a LinkInfo of 0x%x bytes", linkInfoHeaderSize, lnk.LinkInfoSize), ErrInvalidSize)
+		}
+		linkInfoFlags := endianness.Uint32(linkInfo[8:])
+		lnk.VolumeIDAndLocalBasePath = linkInfoFlags&(1<<0) != 0
+		lnk.CommonNetworkRelativeLinkAndPathSuffix = linkInfoFlags&(1<<1) != 0
+		volumeIDOffset := endianness.Uint32(linkInfo[12:])
+		localBasePathOffset := endianness.Uint32(linkInfo[16:])
+		commonNetworkRelativeLinkOffset := endianness.Uint32(linkInfo[20:])
+		commonPathSuffixOffset := endianness.Uint32(linkInfo[24:])
+		// the Unicode offsets are only present in headers of 0x24 bytes or
+		// more, and an offset of zero means the string is absent
+		var localBasePathOffsetUnicode, commonPathSuffixOffsetUnicode uint32
+		if linkInfoHeaderSize >= 0x24 {
+			localBasePathOffsetUnicode = endianness.Uint32(linkInfo[28:])
+			commonPathSuffixOffsetUnicode = endianness.Uint32(linkInfo[32:])
 		}
 
-		if linkInfoHeaderSize > 32 {
-			// CommonPathSuffixOffsetUnicode
-			_, err = file.Discard(4)
-			if err != nil {
-				return lnk, err
+		// a header larger than the fields above is a later extension whose
+		// fields are not understood; as the structures are located by their
+		// offsets, it is skipped, but they must not overlap it
+		for _, structure := range []struct {
+			present bool
+			offset  uint32
+			name    string
+		}{
+			{lnk.VolumeIDAndLocalBasePath, volumeIDOffset, "VolumeID"},
+			{lnk.VolumeIDAndLocalBasePath, localBasePathOffset, "LocalBasePath"},
+			{lnk.CommonNetworkRelativeLinkAndPathSuffix, commonNetworkRelativeLinkOffset, "CommonNetworkRelativeLink"},
+			{commonPathSuffixOffset != 0, commonPathSuffixOffset, "CommonPathSuffix"},
+		} {
+			if structure.present && structure.offset < linkInfoHeaderSize {
+				msg := fmt.Sprintf("%s at offset 0x%x overlaps the LinkInfo header of 0x%x bytes", structure.name, structure.offset, linkInfoHeaderSize)
+				return lnk, malformed(SectionLinkInfo, start+int64(structure.offset), msg, ErrInvalidSize)
 			}
 		}
 
 		if lnk.VolumeIDAndLocalBasePath {
-			var volumeIDSize uint32
-			err = binary.Read(file, endianness, &volumeIDSize)
-			if err != nil {
-				return lnk, err
+			// VolumeID (MS-SHLLINK 2.3.1)
+			if volumeIDOffset > lnk.LinkInfoSize-0x10 {
+				return lnk, malformed(SectionLinkInfo, start+12, fmt.Sprintf("VolumeIDOffset 0x%x is out of bounds", volumeIDOffset), ErrInvalidSize)
 			}
-			if volumeIDSize <= 0x00000010 {
-				return lnk, ErrInvalidSize
+			volumeIDSize := endianness.Uint32(linkInfo[volumeIDOffset:])
+			if volumeIDSize <= 0x10 || volumeIDSize > lnk.LinkInfoSize-volumeIDOffset {
+				return lnk, malformed(SectionLinkInfo, start+int64(volumeIDOffset), fmt.Sprintf("VolumeIDSize 0x%x is out of bounds", volumeIDSize), ErrInvalidSize)
 			}
 
-			err = binary.Read(file, endianness, &lnk.DriveType)
-			if err != nil {
-				return lnk, err
+			// the label is located within the structure rather than read up
+			// to a NUL, which could run into the following fields
+			volumeID := linkInfo[volumeIDOffset : volumeIDOffset+volumeIDSize]
+			lnk.DriveType = endianness.Uint32(volumeID[4:])
+			lnk.DriveSerialNumber = endianness.Uint32(volumeID[8:])
+			volumeLabelOffset := endianness.Uint32(volumeID[12:])
+			if volumeLabelOffset < 0x10 || volumeLabelOffset > volumeIDSize {
+				return lnk, malformed(SectionLinkInfo, start+int64(volumeIDOffset)+12, fmt.Sprintf("VolumeLabelOffset 0x%x is out of bounds", volumeLabelOffset), ErrInvalidSize)
+			}
+			// an offset of 0x14 means VolumeLabelOffsetUnicode follows, and
+			// that the label is only stored in Unicode
+			if volumeLabelOffset == 0x14 {
+				volumeLabelOffsetUnicode := endianness.Uint32(volumeID[0x10:])
+				// a zero offset means there is no label
+				if volumeLabelOffsetUnicode != 0 {
+					if volumeLabelOffsetUnicode < 0x14 || volumeLabelOffsetUnicode > volumeIDSize {
+						return lnk, malformed(SectionLinkInfo, start+int64(volumeIDOffset)+0x10, fmt.Sprintf("VolumeLabelOffsetUnicode 0x%x is out of bounds", volumeLabelOffsetUnicode), ErrInvalidSize)
+					}
+					lnk.VolumeLabelUnicode = decodeUTF16(volumeID[volumeLabelOffsetUnicode:])
+					lnk.VolumeLabel = lnk.VolumeLabelUnicode
+					if hasTrailingData(volumeID[volumeLabelOffsetUnicode:], 2) {
+						err = lnk.warn(opts.Strict, SectionLinkInfo, start+int64(volumeIDOffset+volumeLabelOffsetUnicode), "VolumeLabel is followed by data after its terminating NUL", ErrTrailingData)
+						if err != nil {
+							return lnk, err
+						}
+					}
+				}
+			} else {
+				lnk.VolumeLabelANSI = cString(volumeID[volumeLabelOffset:])
+				lnk.VolumeLabel = lnk.VolumeLabelANSI
+				if hasTrailingData(volumeID[volumeLabelOffset:], 1) {
+					err = lnk.warn(opts.Strict, SectionLinkInfo, start+int64(volumeIDOffset+volumeLabelOffset), "VolumeLabel is followed by data after its terminating NUL", ErrTrailingData)
+					if err != nil {
+						return lnk, err
+					}
+				}
 			}
+		}
 
-			err = binary.Read(file, endianness, &lnk.DriveSerialNumber)
-			if err != nil {
-				return lnk, err
+		// LocalBasePath is read whenever it has a valid offset, even if the
+		// flag that should accompany it is clear, as some writers leave out
+		// the VolumeID but not the path; it is only required with the flag
+		validOffset := func(offset uint32) bool {
+			return offset >= linkInfoHeaderSize && offset < lnk.LinkInfoSize
+		}
+		if lnk.VolumeIDAndLocalBasePath && !validOffset(localBasePathOffset) {
+			return lnk, malformed(SectionLinkInfo, start+16, fmt.Sprintf("LocalBasePathOffset 0x%x is out of bounds", localBasePathOffset), ErrInvalidSize)
+		}
+		if lnk.VolumeIDAndLocalBasePath && localBasePathOffsetUnicode != 0 && !validOffset(localBasePathOffsetUnicode) {
+			return lnk, malformed(SectionLinkInfo, start+28, fmt.Sprintf("LocalBasePathOffsetUnicode 0x%x is out of bounds", localBasePathOffsetUnicode), ErrInvalidSize)
+		}
+		if validOffset(localBasePathOffset) {
+			lnk.LocalBasePathANSI = cString(linkInfo[localBasePathOffset:])
+			lnk.LocalBasePath = lnk.LocalBasePathANSI
+			if validOffset(localBasePathOffsetUnicode) {
+				lnk.LocalBasePathUnicode = decodeUTF16(linkInfo[localBasePathOffsetUnicode:])
 			}
+		}
 
-			var volumeLabelOffset uint32
-			err = binary.Read(file, endianness, &volumeLabelOffset)
-			if err != nil {
-				return lnk, err
+		if lnk.CommonNetworkRelativeLinkAndPathSuffix {
+			// CommonNetworkRelativeLink (MS-SHLLINK 2.3.2)
+			if commonNetworkRelativeLinkOffset > lnk.LinkInfoSize-0x14 {
+				return lnk, malformed(SectionLinkInfo, start+20, fmt.Sprintf("CommonNetworkRelativeLinkOffset 0x%x is out of bounds", commonNetworkRelativeLinkOffset), ErrInvalidSize)
+			}
+			cnrlStart := start + int64(commonNetworkRelativeLinkOffset)
+			cnrl := linkInfo[commonNetworkRelativeLinkOffset:]
+			cnrlSize := endianness.Uint32(cnrl)
+			if cnrlSize < 0x14 || cnrlSize > uint32(len(cnrl)) {
+				return lnk, malformed(SectionLinkInfo, cnrlStart, fmt.Sprintf("CommonNetworkRelativeLinkSize 0x%x is out of bounds", cnrlSize), ErrInvalidSize)
 			}
+			cnrl = cnrl[:cnrlSize]
 
-			if volumeLabelOffset > 16 {
-				// VolumeLabelOffsetUnicode
-				_, err = file.Discard(4)
-				if err != nil {
-					return lnk, err
+			cnrlFlags := endianness.Uint32(cnrl[4:])
+			lnk.ValidDevice = cnrlFlags&(1<<0) != 0
+			lnk.ValidNetType = cnrlFlags&(1<<1) != 0
+
+			netNameOffset := endianness.Uint32(cnrl[8:])
+			if netNameOffset < 0x14 || netNameOffset >= cnrlSize {
+				return lnk, malformed(SectionLinkInfo, cnrlStart+8, fmt.Sprintf("NetNameOffset 0x%x is out of bounds", netNameOffset), ErrInvalidSize)
+			}
+			lnk.NetNameANSI = cString(cnrl[netNameOffset:])
+			lnk.NetName = lnk.NetNameANSI
+			// the Unicode offsets are only present if NetNameOffset leaves
+			// room for them
+			unicode := netNameOffset > 0x14 && cnrlSize >= 0x1c
+			if unicode {
+				netNameOffsetUnicode := endianness.Uint32(cnrl[0x14:])
+				if netNameOffsetUnicode != 0 {
+					if netNameOffsetUnicode >= cnrlSize {
+						return lnk, malformed(SectionLinkInfo, cnrlStart+0x14, fmt.Sprintf("NetNameOffsetUnicode 0x%x is out of bounds", netNameOffsetUnicode), ErrInvalidSize)
+					}
+					lnk.NetNameUnicode = decodeUTF16(cnrl[netNameOffsetUnicode:])
 				}
 			}
 
-			lnk.VolumeLabel, err = file.ReadString('\x00')
-			if err != nil {
-				return lnk, err
+			// DeviceName and NetworkProviderType are only meaningful if
+			// they are flagged as valid
+			if lnk.ValidDevice {
+				deviceNameOffset := endianness.Uint32(cnrl[12:])
+				if deviceNameOffset < 0x14 || deviceNameOffset >= cnrlSize {
+					return lnk, malformed(SectionLinkInfo, cnrlStart+12, fmt.Sprintf("DeviceNameOffset 0x%x is out of bounds", deviceNameOffset), ErrInvalidSize)
+				}
+				lnk.DeviceNameANSI = cString(cnrl[deviceNameOffset:])
+				lnk.DeviceName = lnk.DeviceNameANSI
+				if unicode {
+					deviceNameOffsetUnicode := endianness.Uint32(cnrl[0x18:])
+					if deviceNameOffsetUnicode != 0 {
+						if deviceNameOffsetUnicode >= cnrlSize {
+							return lnk, malformed(SectionLinkInfo, cnrlStart+0x18, fmt.Sprintf("DeviceNameOffsetUnicode 0x%x is out of bounds", deviceNameOffsetUnicode), ErrInvalidSize)
+						}
+						lnk.DeviceNameUnicode = decodeUTF16(cnrl[deviceNameOffsetUnicode:])
+					}
+				}
 			}
-			lnk.VolumeLabel = strings.Trim(lnk.VolumeLabel, "\x00")
+			if lnk.ValidNetType {
+				lnk.NetworkProviderType = endianness.Uint32(cnrl[16:])
+			}
+		}
 
-			lnk.LocalBasePath, err = file.ReadString('\x00')
-			if err != nil {
-				return lnk, err
+		if commonPathSuffixOffset != 0 {
+			if commonPathSuffixOffset >= lnk.LinkInfoSize {
+				return lnk, malformed(SectionLinkInfo, start+24, fmt.Sprintf("CommonPathSuffixOffset 0x%x is out of bounds", commonPathSuffixOffset), ErrInvalidSize)
+			}
+			lnk.CommonPathSuffixANSI = cString(linkInfo[commonPathSuffixOffset:])
+			lnk.CommonPathSuffix = lnk.CommonPathSuffixANSI
+		}
+		if commonPathSuffixOffsetUnicode != 0 {
+			if commonPathSuffixOffsetUnicode < linkInfoHeaderSize || commonPathSuffixOffsetUnicode >= lnk.LinkInfoSize {
+				return lnk, malformed(SectionLinkInfo, start+32, fmt.Sprintf("CommonPathSuffixOffsetUnicode 0x%x is out of bounds", commonPathSuffixOffsetUnicode), ErrInvalidSize)
 			}
-			lnk.LocalBasePath = strings.Trim(lnk.LocalBasePath, "\x00")
+			lnk.CommonPathSuffixUnicode = decodeUTF16(linkInfo[commonPathSuffixOffsetUnicode:])
 		}
 	}
+	lnk.Parsed |= SectionLinkInfo
+	logSection("LinkInfo")
+	lnk.RawLinkInfo = file.take()
+	if opts.targetOnly {
+		return lnk, nil
+	}
+
+	// StringData
+	err = readStringData(file, lnk)
+	if err != nil {
+		return lnk, err
+	}
+	lnk.Parsed |= SectionStringData
+	logSection("StringData")
+	lnk.RawStringData = file.take()
+
+	if opts.SkipExtraData {
+		return lnk, nil
+	}
+
+	// ExtraData
+	if opts.Lazy {
+		lnk.lazy = &lazyExtraData{offset: start, opts: opts}
+		lnk.lazy.data, err = skipExtraData(file, lnk, opts, start)
+	} else {
+		err = readExtraData(file, lnk, opts, start)
+	}
+	if err != nil {
+		return lnk, err
+	}
+	lnk.Parsed |= SectionExtraData
+	lnk.EndOffset = file.n
+	logSection("ExtraData")
+	lnk.RawExtraData = file.take()
 
 	return lnk, nil
 }
+
+// the default ParseOptions.MaxStructureSize
+const defaultMaxStructureSize = 16 << 20
+
+// checkSize returns an ErrTooLarge ParseError if size, the value of the size
+// field named name at offset, exceeds opts.MaxStructureSize.
+func (opts ParseOptions) checkSize(section Section, offset int64, name string, size uint32) error {
+	limit := int64(opts.MaxStructureSize)
+	if limit <= 0 {
+		limit = defaultMaxStructureSize
+	}
+	if int64(size) > limit {
+		return malformed(section, offset, fmt.Sprintf("%s 0x%x exceeds the maximum of 0x%x", name, size, limit), ErrTooLarge)
+	}
+	return nil
+}
+
+// readBytes reads exactly n bytes. Memory is allocated as data arrives rather
+// than up front, so a corrupt size cannot cause a huge allocation.
+func readBytes(file io.Reader, n int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(file, n))
+	if err != nil {
+		return data, err
+	}
+	if int64(len(data)) < n {
+		return data, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// the number of bytes read between calls to ParseOptions.Progress
+const progressChunkSize = 4096
+
+// readBytesProgress is readBytes, calling progress after each chunk.
+func readBytesProgress(file io.Reader, n int64, progress func(bytesRead, total int64)) ([]byte, error) {
+	var data []byte
+	for int64(len(data)) < n {
+		chunk, err := readBytes(file, min(n-int64(len(data)), progressChunkSize))
+		data = append(data, chunk...)
+		progress(int64(len(data)), n)
+		if err != nil {
+			return data, err
+		}
+	}
+	return data, nil
+}
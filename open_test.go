@@ -0,0 +1,566 @@
+package lnk
+
+import (
+	"bytes"
+	"embed"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseErrorOffset(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{RetainRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkInfo := len(lnk.RawHeader) + len(lnk.RawIDList)
+	extraData := len(data) - len(lnk.RawExtraData)
+
+	tests := []struct {
+		name   string
+		modify func(data []byte)
+		offset int64
+		err    error
+	}{
+		{"reserved FileAttributes", func(data []byte) { data[24] |= 1 << 3 }, 24, ErrReservedBitSet},
+		{"HotKey", func(data []byte) { data[64] = 0x01 }, 64, ErrInvalidHotKey},
+		{"reserved header field", func(data []byte) { data[70] = 1 }, 66, ErrReservedBitSet},
+		{"LinkInfoSize", func(data []byte) { endianness.PutUint32(data[linkInfo:], 0x10) }, int64(linkInfo), ErrInvalidSize},
+		{"LinkInfoHeaderSize", func(data []byte) { endianness.PutUint32(data[linkInfo+4:], 0x10) }, int64(linkInfo + 4), ErrInvalidSize},
+		{"VolumeIDOffset", func(data []byte) { endianness.PutUint32(data[linkInfo+12:], 0xffff) }, int64(linkInfo + 12), ErrInvalidSize},
+		{"CommonPathSuffixOffset", func(data []byte) { endianness.PutUint32(data[linkInfo+24:], 0xffff) }, int64(linkInfo + 24), ErrInvalidSize},
+		{"BlockSize", func(data []byte) { endianness.PutUint32(data[extraData:], 6) }, int64(extraData), ErrInvalidSize},
+		{"huge LinkInfoSize", func(data []byte) { endianness.PutUint32(data[linkInfo:], 0xffffffff) }, int64(linkInfo), ErrTooLarge},
+		{"huge BlockSize", func(data []byte) { endianness.PutUint32(data[extraData:], 0xffffffff) }, int64(extraData), ErrTooLarge},
+	}
+	for _, test := range tests {
+		modified := append([]byte(nil), data...)
+		test.modify(modified)
+		_, err := Parse(bytes.NewReader(modified))
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%s: error = %v, want a ParseError", test.name, err)
+			continue
+		}
+		if parseErr.Offset != test.offset {
+			t.Errorf("%s: Offset = %d, want %d", test.name, parseErr.Offset, test.offset)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestParseMaxStructureSize(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	// the largest ExtraData block of local.lnk is 0x314 bytes
+	for _, lazy := range []bool{false, true} {
+		_, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{MaxStructureSize: 0x313, Lazy: lazy})
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("lazy %v: error = %v, want %v", lazy, err, ErrTooLarge)
+		}
+		if _, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{MaxStructureSize: 0x314, Lazy: lazy}); err != nil {
+			t.Errorf("lazy %v: %v", lazy, err)
+		}
+	}
+}
+
+func TestParseOversizedIDList(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	// the IDList, LinkInfo and the rest leave fewer than 0xffff bytes
+	modified := append([]byte(nil), data...)
+	endianness.PutUint16(modified[76:], 0xffff)
+	remain := len(data) - 78
+	want := fmt.Sprintf("offset 76: IDList declares 65535 bytes but only %d remain", remain)
+
+	_, err := Parse(bytes.NewReader(modified))
+	if err == nil || err.Error() != want {
+		t.Errorf("Parse error = %v, want %q", err, want)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Parse error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	_, err = QuickTarget(bytes.NewReader(modified))
+	if err == nil || err.Error() != want {
+		t.Errorf("QuickTarget error = %v, want %q", err, want)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	data := readTestdata(t, "local.lnk")
+	if _, err := Parse(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("empty: error = %v, want %v", err, io.EOF)
+	}
+	for end := 1; end < len(data); end++ {
+		_, err := Parse(bytes.NewReader(data[:end]))
+		if err == io.EOF {
+			t.Errorf("cut at %d: io.EOF", end)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	files, err := filepath.Glob("testdata/*.lnk")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lnk, err := Parse(bytes.NewReader(data))
+		if err == io.EOF && len(data) != 0 {
+			t.Fatal("io.EOF for a partial shortcut")
+		}
+		if err != nil {
+			return
+		}
+
+		lnk.ResolveTarget()
+		lnk.KnownFolderChildPath()
+		lnk.Dump(io.Discard, DumpOptions{})
+		if _, err := json.Marshal(lnk); err != nil {
+			t.Fatal(err)
+		}
+
+		// whatever is parsed can be written and parsed again
+		var buf bytes.Buffer
+		if _, err := lnk.WriteTo(&buf); err != nil {
+			return
+		}
+		if _, err := Parse(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("parsing the written shortcut: %v", err)
+		}
+	})
+}
+
+func TestParseSkipExtraData(t *testing.T) {
+	data := readTestdata(t, "console.lnk")
+	lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{SkipExtraData: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.Console != nil || lnk.Parsed&SectionExtraData != 0 {
+		t.Errorf("Console = %v, Parsed = %v, want no ExtraData", lnk.Console, lnk.Parsed)
+	}
+	if lnk.Arguments != "/k ver" || lnk.LocalBasePath != `C:\Windows\System32\cmd.exe` {
+		t.Errorf("Arguments = %q, LocalBasePath = %q", lnk.Arguments, lnk.LocalBasePath)
+	}
+}
+
+// corpus returns the contents of the shortcuts in testdata.
+func corpus(b *testing.B) [][]byte {
+	files, err := filepath.Glob("testdata/*.lnk")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var shortcuts [][]byte
+	for _, file := range files {
+		shortcuts = append(shortcuts, readTestdata(b, filepath.Base(file)))
+	}
+	return shortcuts
+}
+
+func benchmarkParseCorpus(b *testing.B, opts ParseOptions) {
+	shortcuts := corpus(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range shortcuts {
+			ParseWithOptions(bytes.NewReader(data), opts)
+		}
+	}
+}
+
+func BenchmarkParseCorpus(b *testing.B) {
+	benchmarkParseCorpus(b, ParseOptions{})
+}
+
+func BenchmarkParseCorpusSkipExtraData(b *testing.B) {
+	benchmarkParseCorpus(b, ParseOptions{SkipExtraData: true})
+}
+
+func ExampleParseOptions_logger() {
+	data, err := os.ReadFile("testdata/local.lnk")
+	if err != nil {
+		panic(err)
+	}
+
+	// a text handler that omits the time, for stable output
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+	_, err = ParseWithOptions(bytes.NewReader(data), ParseOptions{Logger: logger})
+	if err != nil {
+		panic(err)
+	}
+	// Output:
+	// level=DEBUG msg="parsed section" section=ShellLinkHeader offset=0 size=76
+	// level=DEBUG msg="parsed section" section=LinkTargetIDList offset=76 size=207
+	// level=DEBUG msg="parsed section" section=LinkInfo offset=283 size=64
+	// level=DEBUG msg="parsed section" section=StringData offset=347 size=50
+	// level=DEBUG msg="parsed section" section=ExtraData offset=397 size=836
+}
+
+//go:embed testdata/*.lnk
+var embedded embed.FS
+
+func TestParseFS(t *testing.T) {
+	lnk, err := ParseFS(embedded, "testdata/local.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lnk.LocalBasePath != `C:\test\a.txt` {
+		t.Errorf("LocalBasePath = %q", lnk.LocalBasePath)
+	}
+
+	if _, err := ParseFS(embedded, "testdata/missing.lnk"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+// fieldHeader is the ShellLinkHeader as it was decoded before it was read at
+// once, field by field with binary.Read.
+type fieldHeader struct {
+	HeaderSize     uint32
+	CLSID          [16]byte
+	LinkFlags      uint32
+	FileAttributes uint32
+	CreationTime   uint64
+	AccessTime     uint64
+	WriteTime      uint64
+	FileSize       uint32
+	IconIndex      int32
+	ShowCommand    uint32
+	HotKeyLow      uint8
+	HotKeyHigh     uint8
+	Reserved1      uint16
+	Reserved2      uint32
+	Reserved3      uint32
+}
+
+func TestParseHeaderMatchesFieldDecode(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data := readTestdata(t, filepath.Base(file))
+		var header fieldHeader
+		if err := binary.Read(bytes.NewReader(data), endianness, &header); err != nil {
+			t.Fatal(err)
+		}
+		lnk := load(t, filepath.Base(file))
+
+		want := HotKey{
+			Key:      header.HotKeyLow,
+			Shift:    header.HotKeyHigh&1 != 0,
+			Ctrl:     header.HotKeyHigh&2 != 0,
+			Alt:      header.HotKeyHigh&4 != 0,
+			Reserved: header.HotKeyHigh &^ 7,
+		}
+		if GUID(header.CLSID) != lnk.CLSID || header.LinkFlags != lnk.LinkFlags || header.FileAttributes != lnk.FileAttributes ||
+			!windowsNanoToTime(header.CreationTime).Equal(lnk.CreationTime) ||
+			!windowsNanoToTime(header.AccessTime).Equal(lnk.AccessTime) ||
+			!windowsNanoToTime(header.WriteTime).Equal(lnk.WriteTime) ||
+			header.FileSize != lnk.FileSize || header.IconIndex != lnk.IconIndex || header.ShowCommand != lnk.ShowCommand ||
+			want != lnk.HotKey {
+			t.Errorf("%s: header decoded as %+v, want %+v", file, lnk, header)
+		}
+	}
+}
+
+// headerOnly returns a shortcut that consists of a header.
+func headerOnly(b *testing.B) []byte {
+	data := append([]byte(nil), readTestdata(b, "local.lnk")[:76]...)
+	endianness.PutUint32(data[20:], 0)
+	return data
+}
+
+func BenchmarkParseHeader(b *testing.B) {
+	data := headerOnly(b)
+	r := bytes.NewReader(data)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		if _, err := ParseWithOptions(r, ParseOptions{SkipExtraData: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseHeaderFieldByField decodes the header as it was before, for
+// comparison with BenchmarkParseHeader.
+func BenchmarkParseHeaderFieldByField(b *testing.B) {
+	data := headerOnly(b)
+	r := bytes.NewReader(data)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		var header fieldHeader
+		for _, field := range []interface{}{
+			&header.HeaderSize, &header.CLSID, &header.LinkFlags, &header.FileAttributes,
+			&header.CreationTime, &header.AccessTime, &header.WriteTime, &header.FileSize,
+			&header.IconIndex, &header.ShowCommand, &header.HotKeyLow, &header.HotKeyHigh,
+			&header.Reserved1, &header.Reserved2, &header.Reserved3,
+		} {
+			if err := binary.Read(r, endianness, field); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestParser(t *testing.T) {
+	var p Parser
+	for _, name := range []string{"local.lnk", "uwp.lnk", "console.lnk", "local.lnk"} {
+		data := readTestdata(t, name)
+		got, err := p.Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gotJSON, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantJSON, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("%s: Parser.Parse() = %s, want %s", name, gotJSON, wantJSON)
+		}
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	shortcuts := corpus(b)
+	var p Parser
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range shortcuts {
+			p.Parse(bytes.NewReader(data))
+		}
+	}
+}
+
+func TestQuickTarget(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+		err  error
+	}{
+		{"local.lnk", `C:\test\a.txt`, nil},
+		{"unc.lnk", `\\server\share\file.txt`, nil},
+		{"mapped.lnk", `Z:\docs\f.txt`, nil},
+		{"emoji_li.lnk", "C:\\\U0001F600.txt", nil},
+		{"uwp.lnk", "", ErrNoTarget},
+	}
+	for _, test := range tests {
+		got, err := QuickTarget(bytes.NewReader(readTestdata(t, test.file)))
+		if got != test.want || err != test.err {
+			t.Errorf("%s: QuickTarget() = %q, %v, want %q, %v", test.file, got, err, test.want, test.err)
+		}
+	}
+
+	// nothing past the LinkInfo is read
+	r := bytes.NewReader(readTestdata(t, "local.lnk"))
+	if _, err := QuickTarget(r); err != nil {
+		t.Fatal(err)
+	}
+	if offset := r.Size() - int64(r.Len()); offset != 347 {
+		t.Errorf("QuickTarget() read up to %d, want 347", offset)
+	}
+}
+
+func BenchmarkQuickTarget(b *testing.B) {
+	shortcuts := corpus(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range shortcuts {
+			QuickTarget(bytes.NewReader(data))
+		}
+	}
+}
+
+func TestParseAllowForeignCLSID(t *testing.T) {
+	data := append([]byte(nil), readTestdata(t, "local.lnk")...)
+	// Data1 of the CLSID in the wrong byte order
+	data[4], data[5], data[6], data[7] = data[7], data[6], data[5], data[4]
+
+	for _, opts := range []ParseOptions{{}, {Strict: true}} {
+		if _, err := ParseWithOptions(bytes.NewReader(data), opts); !errors.Is(err, ErrInvalidCLSID) {
+			t.Errorf("strict %v: error = %v, want %v", opts.Strict, err, ErrInvalidCLSID)
+		}
+	}
+
+	for _, opts := range []ParseOptions{{AllowForeignCLSID: true}, {AllowForeignCLSID: true, Strict: true}} {
+		lnk, err := ParseWithOptions(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatalf("strict %v: %v", opts.Strict, err)
+		}
+		const want = "{01140200-0000-0000-C000-000000000046}"
+		if lnk.CLSIDString() != want || len(lnk.Warnings()) != 1 {
+			t.Errorf("strict %v: CLSIDString() = %s, Warnings() = %q, want %s and one warning", opts.Strict, lnk.CLSIDString(), lnk.Warnings(), want)
+		}
+		if lnk.LocalBasePath != `C:\test\a.txt` {
+			t.Errorf("strict %v: LocalBasePath = %q", opts.Strict, lnk.LocalBasePath)
+		}
+	}
+}
+
+func TestParseEndOffset(t *testing.T) {
+	for _, name := range []string{"local.lnk", "tracker.lnk", "uwp.lnk"} {
+		data := readTestdata(t, name)
+		// 100 bytes appended after the TerminalBlock
+		overlay := append(append([]byte(nil), data...), bytes.Repeat([]byte{0xcc}, 100)...)
+
+		for _, opts := range []ParseOptions{{}, {Lazy: true}} {
+			lnk, err := ParseWithOptions(bytes.NewReader(overlay), opts)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if lnk.EndOffset != int64(len(data)) {
+				t.Errorf("%s: EndOffset = %d, want %d", name, lnk.EndOffset, len(data))
+			}
+			if appended := int64(len(overlay)) - lnk.EndOffset; appended != 100 {
+				t.Errorf("%s: %d bytes appended, want 100", name, appended)
+			}
+		}
+
+		// the end is unknown if the ExtraData is not read
+		lnk, err := ParseWithOptions(bytes.NewReader(overlay), ParseOptions{SkipExtraData: true})
+		if err != nil || lnk.EndOffset != 0 {
+			t.Errorf("%s: EndOffset = %d, %v with SkipExtraData, want 0", name, lnk.EndOffset, err)
+		}
+	}
+}
+
+// largeShortcut returns a 256 KB shortcut: the IDListSize is 16 bits, so its
+// IDList is as large as it may be, and a vendor block makes up the rest.
+func largeShortcut(tb testing.TB) []byte {
+	lnk := NewBuilder().Build()
+	item := append([]byte{0x00, 0x01, 0x31}, bytes.Repeat([]byte{'x'}, 253)...)
+	lnk.IDListBytes = append(bytes.Repeat(item, 255), 0, 0)
+	lnk.UnknownBlocks = []ExtraDataBlock{{Signature: 0x12345678}}
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	lnk.UnknownBlocks[0].Data = make([]byte, 256<<10-len(data))
+	if data, err = lnk.MarshalBinary(); err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+// readCounter counts the reads from r.
+type readCounter struct {
+	r     io.Reader
+	reads int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.reads++
+	return r.r.Read(p)
+}
+
+func TestParseProgress(t *testing.T) {
+	var calls, last, total int64
+	_, err := ParseWithOptions(bytes.NewReader(largeShortcut(t)), ParseOptions{
+		Progress: func(bytesRead, n int64) {
+			calls++
+			last, total = bytesRead, n
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 65282 bytes of IDList in chunks of 4096
+	if calls != 16 || last != 65282 || total != 65282 {
+		t.Errorf("Progress called %d times, last with %d of %d bytes, want 16 times with 65282 of 65282", calls, last, total)
+	}
+}
+
+func TestParseBufferSize(t *testing.T) {
+	data := largeShortcut(t)
+	if len(data) != 256<<10 {
+		t.Fatalf("shortcut is %d bytes", len(data))
+	}
+
+	reads := make(map[int]int)
+	for _, size := range []int{0, 64 << 10, 1} {
+		r := &readCounter{r: bytes.NewReader(data)}
+		lnk, err := ParseWithOptions(r, ParseOptions{BufferSize: size})
+		if err != nil {
+			t.Fatalf("BufferSize %d: %v", size, err)
+		}
+		if len(lnk.IDListBytes) != 65282 || len(lnk.UnknownBlocks) != 1 || lnk.EndOffset != int64(len(data)) {
+			t.Errorf("BufferSize %d: IDList of %d bytes, %d unknown blocks, EndOffset %d", size, len(lnk.IDListBytes), len(lnk.UnknownBlocks), lnk.EndOffset)
+		}
+		reads[size] = r.reads
+
+		var p Parser
+		p.Options.BufferSize = size
+		if _, err := p.Parse(bytes.NewReader(data)); err != nil {
+			t.Errorf("Parser with BufferSize %d: %v", size, err)
+		}
+	}
+	if reads[64<<10] >= reads[0] {
+		t.Errorf("%d reads with a 64 KB buffer, %d with the default", reads[64<<10], reads[0])
+	}
+}
+
+func BenchmarkParseBufferSize(b *testing.B) {
+	data := largeShortcut(b)
+	for _, bench := range []struct {
+		name string
+		size int
+	}{
+		{"default", 0},
+		{"64KB", 64 << 10},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			var reads int
+			for i := 0; i < b.N; i++ {
+				r := &readCounter{r: bytes.NewReader(data)}
+				if _, err := ParseWithOptions(r, ParseOptions{BufferSize: bench.size}); err != nil {
+					b.Fatal(err)
+				}
+				reads += r.reads
+			}
+			// the reads are what a larger buffer saves on slow media
+			b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package lnk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// propertyStorageVersion is the Version of a serialized property storage,
+// "1SPS" in little-endian.
+const propertyStorageVersion = 0x53505331
+
+// stringNamedFormatID is the FormatID of property storages whose values are
+// identified by name rather than by integer ID.
+var stringNamedFormatID = guid("D5CDD505-2E9C-101B-9397-08002B2CF9AE")
+
+// appUserModelIDKey is the key of the System.AppUserModel.ID property.
+var appUserModelIDKey = PropertyKey{guid("9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3"), 5}
+
+// VARTYPEs of property values that are decoded.
+const (
+	vtEmpty    = 0x00
+	vtNull     = 0x01
+	vtI2       = 0x02
+	vtI4       = 0x03
+	vtBSTR     = 0x08
+	vtBool     = 0x0b
+	vtUI1      = 0x11
+	vtUI2      = 0x12
+	vtUI4      = 0x13
+	vtI8       = 0x14
+	vtUI8      = 0x15
+	vtInt      = 0x16
+	vtUInt     = 0x17
+	vtLPSTR    = 0x1e
+	vtLPWSTR   = 0x1f
+	vtFileTime = 0x40
+	vtBlob     = 0x41
+	vtCLSID    = 0x48
+)
+
+// PropertyKey identifies a property by the GUID of its property set and its
+// integer ID.
+type PropertyKey struct {
+	FormatID GUID
+	ID       uint32
+}
+
+// Property is a single value of a serialized property storage
+// (MS-PROPSTORE 2.2).
+type Property struct {
+	PropertyKey
+	// Name identifies the property instead of ID in string-named storages.
+	Name string
+	// Type is the VARTYPE of the value.
+	Type uint16
+	// Value is the decoded value: a string, bool, integer, time.Time or
+	// GUID, or the raw bytes for types that are not decoded.
+	Value interface{}
+}
+
+// Property returns the value of the property identified by key from the
+// PropertyStoreDataBlock.
+func (lnk *LNK) Property(key PropertyKey) (interface{}, bool) {
+	lnk.LoadExtraData()
+	for _, prop := range lnk.PropertyStore {
+		if prop.Name == "" && prop.PropertyKey == key {
+			return prop.Value, true
+		}
+	}
+	return nil, false
+}
+
+// AppUserModelID returns the System.AppUserModel.ID property, which
+// identifies the application the shortcut belongs to for taskbar grouping.
+func (lnk *LNK) AppUserModelID() (string, bool) {
+	value, _ := lnk.Property(appUserModelIDKey)
+	id, ok := value.(string)
+	return id, ok && id != ""
+}
+
+// PinIdentity returns the identity the taskbar and Start use to group the
+// shortcut with the windows of its application and with other shortcuts
+// pinned for it. It is the explicit AppUserModelID if there is one.
+// Otherwise, Windows derives an identity from the path of the executable,
+// which is approximated as the hex-encoded SHA-256 hash of NormalizedTarget in
+// lowercase, so that it is the same for shortcuts to the same executable. An
+// empty string is returned if there is neither.
+func (lnk *LNK) PinIdentity() string {
+	if id, ok := lnk.AppUserModelID(); ok {
+		return id
+	}
+	target := lnk.NormalizedTarget()
+	if target == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(strings.ToLower(target)))
+	return hex.EncodeToString(hash[:])
+}
+
+// readPropertyStore decodes a list of serialized property storages, which is
+// terminated by a storage of size zero or by the end of the data.
+func readPropertyStore(data []byte) ([]Property, error) {
+	var props []Property
+	for len(data) >= 4 {
+		storageSize := endianness.Uint32(data)
+		if storageSize == 0 {
+			break
+		}
+		if storageSize < 24 || uint64(storageSize) > uint64(len(data)) {
+			return props, ErrInvalidSize
+		}
+		if endianness.Uint32(data[4:]) != propertyStorageVersion {
+			return props, ErrInvalidPropertyStore
+		}
+
+		var formatID GUID
+		copy(formatID[:], data[8:])
+		values := data[24:storageSize]
+		data = data[storageSize:]
+
+		for len(values) >= 4 {
+			valueSize := endianness.Uint32(values)
+			if valueSize == 0 {
+				break
+			}
+			if valueSize < 9 || uint64(valueSize) > uint64(len(values)) {
+				return props, ErrInvalidSize
+			}
+			value := values[:valueSize]
+			values = values[valueSize:]
+
+			prop := Property{PropertyKey: PropertyKey{FormatID: formatID}}
+			var typed []byte
+			if formatID == stringNamedFormatID {
+				nameSize := endianness.Uint32(value[4:])
+				if uint64(nameSize) > uint64(len(value)-9) {
+					return props, ErrInvalidSize
+				}
+				prop.Name = decodeUTF16(value[9 : 9+nameSize])
+				typed = value[9+nameSize:]
+			} else {
+				prop.ID = endianness.Uint32(value[4:])
+				typed = value[9:]
+			}
+
+			if len(typed) < 4 {
+				return props, ErrInvalidSize
+			}
+			prop.Type = endianness.Uint16(typed)
+			prop.Value = decodeTypedValue(prop.Type, typed[4:])
+			props = append(props, prop)
+		}
+	}
+	return props, nil
+}
+
+// decodeTypedValue decodes the value of a TypedPropertyValue. Types that are
+// not understood, or values that are truncated, are returned as raw bytes.
+func decodeTypedValue(vt uint16, data []byte) interface{} {
+	fixed := map[uint16]int{
+		vtI2: 2, vtI4: 4, vtBool: 2, vtUI1: 1, vtUI2: 2, vtUI4: 4, vtI8: 8,
+		vtUI8: 8, vtInt: 4, vtUInt: 4, vtFileTime: 8, vtCLSID: 16,
+	}
+	if size, ok := fixed[vt]; ok && len(data) < size {
+		return data
+	}
+
+	switch vt {
+	case vtEmpty, vtNull:
+		return nil
+	case vtI2:
+		return int16(endianness.Uint16(data))
+	case vtI4, vtInt:
+		return int32(endianness.Uint32(data))
+	case vtBool:
+		return endianness.Uint16(data) != 0
+	case vtUI1:
+		return data[0]
+	case vtUI2:
+		return endianness.Uint16(data)
+	case vtUI4, vtUInt:
+		return endianness.Uint32(data)
+	case vtI8:
+		return int64(endianness.Uint64(data))
+	case vtUI8:
+		return endianness.Uint64(data)
+	case vtFileTime:
+		return windowsNanoToTime(endianness.Uint64(data))
+	case vtCLSID:
+		var clsid GUID
+		copy(clsid[:], data)
+		return clsid
+	case vtBSTR, vtLPSTR, vtBlob:
+		if len(data) < 4 {
+			return data
+		}
+		size := endianness.Uint32(data)
+		if uint64(size) > uint64(len(data)-4) {
+			return data
+		}
+		if vt == vtBlob {
+			return data[4 : 4+size]
+		}
+		return string(bytes.TrimRight(data[4:4+size], "\x00"))
+	case vtLPWSTR:
+		if len(data) < 4 {
+			return data
+		}
+		length := endianness.Uint32(data)
+		if uint64(length)*2 > uint64(len(data)-4) {
+			return data
+		}
+		return decodeUTF16(data[4 : 4+length*2])
+	}
+	return data
+}
+
+// decodeUTF16 decodes UTF-16LE bytes, stopping at the first NUL. The code
+// units are collected before they are decoded, so that surrogate pairs, as
+// used for emoji, combine into a single rune.
+func decodeUTF16(data []byte) string {
+	chars := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		char := endianness.Uint16(data[i:])
+		if char == 0 {
+			break
+		}
+		chars = append(chars, char)
+	}
+	return string(utf16.Decode(chars))
+}
+
+// encodeUTF16 encodes str as UTF-16, truncated to at most max code units
+// without splitting a surrogate pair, which would otherwise decode as
+// U+FFFD.
+func encodeUTF16(str string, max int) []uint16 {
+	chars := utf16.Encode([]rune(str))
+	if len(chars) <= max {
+		return chars
+	}
+	chars = chars[:max]
+	if utf16.IsSurrogate(rune(chars[max-1])) && chars[max-1] < 0xdc00 {
+		chars = chars[:max-1]
+	}
+	return chars
+}
+
+// writePropertyStore encodes properties as a list of serialized property
+// storages. Consecutive properties with the same FormatID share a storage.
+func writePropertyStore(props []Property) []byte {
+	var buf bytes.Buffer
+	for len(props) != 0 {
+		formatID := props[0].FormatID
+		var values bytes.Buffer
+		for len(props) != 0 && props[0].FormatID == formatID {
+			prop := props[0]
+			props = props[1:]
+
+			var value bytes.Buffer
+			if formatID == stringNamedFormatID {
+				name := append(utf16.Encode([]rune(prop.Name)), 0)
+				write(&value, uint32(2*len(name)))
+				value.WriteByte(0)
+				write(&value, name)
+			} else {
+				write(&value, prop.ID)
+				value.WriteByte(0)
+			}
+			write(&value, prop.Type)
+			// Padding
+			write(&value, uint16(0))
+			value.Write(encodeTypedValue(prop.Type, prop.Value))
+
+			write(&values, uint32(4+value.Len()))
+			values.Write(value.Bytes())
+		}
+		// terminating value
+		write(&values, uint32(0))
+
+		write(&buf, uint32(24+values.Len()))
+		write(&buf, uint32(propertyStorageVersion))
+		buf.Write(formatID[:])
+		buf.Write(values.Bytes())
+	}
+	// terminating storage
+	write(&buf, uint32(0))
+	return buf.Bytes()
+}
+
+// encodeTypedValue is the inverse of decodeTypedValue. Values are padded to
+// a multiple of four bytes.
+func encodeTypedValue(vt uint16, value interface{}) []byte {
+	var buf bytes.Buffer
+	switch value := value.(type) {
+	case string:
+		if vt == vtLPWSTR {
+			chars := append(utf16.Encode([]rune(value)), 0)
+			write(&buf, uint32(len(chars)))
+			write(&buf, chars)
+		} else {
+			write(&buf, uint32(len(value)+1))
+			buf.WriteString(value + "\x00")
+		}
+	case bool:
+		if value {
+			write(&buf, uint16(0xffff))
+		} else {
+			write(&buf, uint16(0))
+		}
+	case time.Time:
+		write(&buf, timeToWindowsNano(value))
+	case []byte:
+		if vt == vtBlob {
+			write(&buf, uint32(len(value)))
+		}
+		buf.Write(value)
+	case nil:
+	default:
+		write(&buf, value)
+	}
+
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,29 @@
+package lnk
+
+import "io"
+
+// countingReader is an io.Reader that counts the bytes read through it, and
+// optionally keeps a copy of them.
+type countingReader struct {
+	r      io.Reader
+	n      int64
+	record bool
+	raw    []byte
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	if cr.record {
+		cr.raw = append(cr.raw, p[:n]...)
+	}
+	return n, err
+}
+
+// take returns the bytes read since the previous call, or nil if recording is
+// disabled.
+func (cr *countingReader) take() []byte {
+	raw := cr.raw
+	cr.raw = nil
+	return raw
+}
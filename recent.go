@@ -0,0 +1,46 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoShortcuts is returned by MostRecent when no shortcut in the folder can
+// be parsed.
+var ErrNoShortcuts = errors.New("no shortcuts")
+
+// MostRecent parses the .lnk files directly in dir, without descending into
+// subfolders, and returns the one with the latest WriteTime along with its
+// file name. Files that fail to parse are skipped. If several share the latest
+// WriteTime, the first in alphabetical order of file name wins. It returns
+// ErrNoShortcuts if none can be parsed.
+func MostRecent(dir string) (*LNK, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fsys := os.DirFS(dir)
+	var latest *LNK
+	var latestName string
+	// entries are sorted by file name, so only a strictly later WriteTime
+	// replaces the latest shortcut
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".lnk") {
+			continue
+		}
+		lnk, err := ParseFS(fsys, entry.Name())
+		if err != nil {
+			continue
+		}
+		if latest == nil || lnk.WriteTime.After(latest.WriteTime) {
+			latest, latestName = lnk, entry.Name()
+		}
+	}
+	if latest == nil {
+		return nil, "", ErrNoShortcuts
+	}
+	return latest, latestName, nil
+}
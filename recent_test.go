@@ -0,0 +1,47 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMostRecent(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, writeTime time.Time) {
+		b := NewBuilder()
+		if err := b.SetTargetPath(`C:\app.exe`); err != nil {
+			t.Fatal(err)
+		}
+		lnk := b.Build()
+		lnk.WriteTime = writeTime
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := lnk.WriteTo(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	write("b.lnk", now)
+	write("a.lnk", now)
+	write("c.LNK", now.Add(-time.Hour))
+	if err := os.WriteFile(filepath.Join(dir, "z.lnk"), []byte("not a shortcut"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lnk, name, err := MostRecent(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "a.lnk" || !lnk.WriteTime.Equal(now) {
+		t.Errorf("MostRecent() = %s written at %v, want a.lnk", name, lnk.WriteTime)
+	}
+
+	if _, _, err := MostRecent(t.TempDir()); err != ErrNoShortcuts {
+		t.Errorf("empty folder: error = %v, want %v", err, ErrNoShortcuts)
+	}
+}
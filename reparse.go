@@ -0,0 +1,51 @@
+package lnk
+
+// FILE_ATTRIBUTE_REPARSE_POINT
+const reparsePointAttribute = 0x400
+
+// IsReparsePoint reports whether the target is a reparse point, such as a
+// symbolic link or a junction, according to either the ReparsePoint attribute
+// in the header or the attributes of the last file entry in the IDList.
+// Shortcut chains that follow such targets can loop.
+func (lnk *LNK) IsReparsePoint() bool {
+	if lnk.ReparsePoint {
+		return true
+	}
+	attributes, ok := lnk.targetItemAttributes()
+	return ok && attributes&reparsePointAttribute != 0
+}
+
+// ReparseKind classifies the reparse point the target is on a best-effort
+// basis, as shortcuts do not record the reparse tag. A file that is a reparse
+// point can only be a symbolic link, so "symlink" is returned for it, while a
+// directory may be either a symbolic link or a junction, so an empty string is
+// returned for it, as for targets that are not reparse points.
+func (lnk *LNK) ReparseKind() string {
+	if !lnk.IsReparsePoint() {
+		return ""
+	}
+
+	directory := lnk.Directory
+	if attributes, ok := lnk.targetItemAttributes(); ok {
+		// FILE_ATTRIBUTE_DIRECTORY
+		directory = attributes&0x10 != 0
+	}
+	if directory {
+		return ""
+	}
+	return "symlink"
+}
+
+// targetItemAttributes returns the file attributes stored in the last ItemID
+// if it is a file entry.
+func (lnk *LNK) targetItemAttributes() (uint16, bool) {
+	items, _ := lnk.ItemIDs()
+	if len(items) == 0 {
+		return 0, false
+	}
+	item := items[len(items)-1]
+	if len(item) < 12 || item[0]&0x70 != 0x30 {
+		return 0, false
+	}
+	return endianness.Uint16(item[10:]), true
+}
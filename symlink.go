@@ -0,0 +1,55 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFileSystemTarget is returned when the target of a shortcut is not a
+// path in the file system, such as a packaged application or a Control Panel
+// item.
+var ErrNotFileSystemTarget = errors.New("target is not a file system path")
+
+// CreateSymlink creates a symbolic link in destDir that points to the target
+// of the shortcut, for migrating shortcuts to another system. name is the file
+// name of the shortcut, and the link is named after it without its extension.
+// The path returned by ResolveTarget is converted to a path on this system by
+// translate, e.g. from `C:\Users\me\Music` to "/home/me/Music"; it returns an
+// empty string for paths that cannot be translated.
+//
+// ErrNotFileSystemTarget is returned, and nothing is created, if the target
+// is not a drive-letter path, a UNC path or a path starting with an
+// environment variable, or if translate returns an empty string.
+func (lnk *LNK) CreateSymlink(destDir, name string, translate func(winPath string) string) error {
+	path, err := lnk.ResolveTarget()
+	if err != nil {
+		return err
+	}
+	if !isFileSystemPath(path) {
+		return ErrNotFileSystemTarget
+	}
+	target := translate(path)
+	if target == "" {
+		return ErrNotFileSystemTarget
+	}
+
+	return os.Symlink(target, filepath.Join(destDir, symlinkName(name)))
+}
+
+// symlinkName returns the name of the symbolic link created for the shortcut
+// with the given file name, which may be a Windows path.
+func symlinkName(name string) string {
+	name = name[strings.LastIndexAny(name, `\/`)+1:]
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// isFileSystemPath reports whether path is a drive-letter path, a UNC path or
+// a path starting with an environment variable.
+func isFileSystemPath(path string) bool {
+	if len(path) >= 2 && path[1] == ':' && ('A' <= path[0]&^0x20 && path[0]&^0x20 <= 'Z') {
+		return true
+	}
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "%")
+}
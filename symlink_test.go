@@ -0,0 +1,56 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// translateMnt translates a drive-letter path to a path under /mnt, as WSL
+// mounts them.
+func translateMnt(winPath string) string {
+	if len(winPath) < 3 || winPath[1] != ':' {
+		return ""
+	}
+	return "/mnt/" + strings.ToLower(winPath[:1]) + "/" + strings.ReplaceAll(winPath[3:], `\`, "/")
+}
+
+func TestCreateSymlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := load(t, "local.lnk").CreateSymlink(dir, `C:\Users\me\Desktop\My App.lnk`, translateMnt); err != nil {
+		t.Fatal(err)
+	}
+	const want = "/mnt/c/test/a.txt"
+	if got, err := os.Readlink(filepath.Join(dir, "My App")); err != nil || got != want {
+		t.Errorf("Readlink() = %q, %v, want %q", got, err, want)
+	}
+
+	if err := load(t, "uwp.lnk").CreateSymlink(dir, "Calculator.lnk", translateMnt); err == nil {
+		t.Error("CreateSymlink() succeeded for a packaged application")
+	}
+	if err := load(t, "unc.lnk").CreateSymlink(dir, "Share.lnk", translateMnt); err != ErrNotFileSystemTarget {
+		t.Errorf("CreateSymlink() error = %v, want %v for an untranslated path", err, ErrNotFileSystemTarget)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "Share")); !os.IsNotExist(err) {
+		t.Errorf("Lstat() error = %v, want the link not to exist", err)
+	}
+}
+
+func TestSymlinkName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"App.lnk", "App"},
+		{"a.b.lnk", "a.b"},
+		{`C:\Users\me\Desktop\My App.lnk`, "My App"},
+		{"dir/Tool.lnk", "Tool"},
+		{"NoExtension", "NoExtension"},
+	}
+	for _, test := range tests {
+		if got := symlinkName(test.name); got != test.want {
+			t.Errorf("symlinkName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package lnk
+
+import (
+	"sort"
+	"time"
+)
+
+// Labels of the events returned by Timeline.
+const (
+	TimelineCreation     = "creation"
+	TimelineAccess       = "access"
+	TimelineWrite        = "write"
+	TimelineTrackerBirth = "tracker birth"
+)
+
+// TimelineEvent is a labeled timestamp.
+type TimelineEvent struct {
+	Label string
+	Time  time.Time
+}
+
+// Timeline returns the timestamps of the target, and the time its birth
+// identifier was generated if it is in a TrackerDataBlock (see OriginHost), in
+// chronological order. Times that are not set are skipped.
+func (lnk *LNK) Timeline() []TimelineEvent {
+	events := []TimelineEvent{
+		{TimelineCreation, lnk.CreationTime},
+		{TimelineAccess, lnk.AccessTime},
+		{TimelineWrite, lnk.WriteTime},
+	}
+	if _, _, created, ok := lnk.OriginHost(); ok {
+		events = append(events, TimelineEvent{TimelineTrackerBirth, created})
+	}
+
+	timeline := events[:0]
+	for _, event := range events {
+		if !event.Time.IsZero() {
+			timeline = append(timeline, event)
+		}
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline
+}
@@ -0,0 +1,95 @@
+package lnk
+
+import (
+	"net"
+	"time"
+)
+
+// TrackerData is the TrackerDataBlock, which Distributed Link Tracking uses to
+// find the target after it is moved (MS-SHLLINK 2.5.10).
+type TrackerData struct {
+	// MachineID is the NetBIOS name of the machine the target was last known
+	// to reside on.
+	MachineID string
+	// DroidVolumeID and DroidFileID identify the target's volume and file
+	// now, while the Birth variants identify them as they were when the
+	// target was created.
+	DroidVolumeID      GUID
+	DroidFileID        GUID
+	BirthDroidVolumeID GUID
+	BirthDroidFileID   GUID
+}
+
+// TrackerDataBlock returns Tracker, decoding ExtraData first if it was
+// deferred by ParseOptions.Lazy. It returns nil if there is no
+// TrackerDataBlock.
+func (lnk *LNK) TrackerDataBlock() (*TrackerData, error) {
+	err := lnk.LoadExtraData()
+	return lnk.Tracker, err
+}
+
+// size of the TrackerDataBlock excluding BlockSize and BlockSignature
+const trackerDataSize = 0x60 - 8
+
+// the UUID epoch, 1582-10-15, in 100-nanosecond intervals before the Unix
+// epoch
+const uuidEpoch = 0x01b21dd213814000
+
+// OriginHost returns the machine the target was created on, from the
+// TrackerDataBlock. The birth file identifier is normally a version 1 UUID,
+// which embeds the MAC address of the machine that generated it and the time
+// it was generated; mac and created are only returned if it is one. ok is
+// false if there is no TrackerDataBlock.
+func (lnk *LNK) OriginHost() (machine string, mac net.HardwareAddr, created time.Time, ok bool) {
+	lnk.LoadExtraData()
+	if lnk.Tracker == nil {
+		return "", nil, time.Time{}, false
+	}
+
+	machine = lnk.Tracker.MachineID
+	id := lnk.Tracker.BirthDroidFileID
+	// the first three fields are little-endian on disk
+	timeLow := uint64(endianness.Uint32(id[0:]))
+	timeMid := uint64(endianness.Uint16(id[4:]))
+	timeHiAndVersion := uint64(endianness.Uint16(id[6:]))
+	// version 1 and the RFC 4122 variant
+	if timeHiAndVersion>>12 != 1 || id[8]&0xc0 != 0x80 {
+		return machine, nil, time.Time{}, true
+	}
+
+	timestamp := (timeHiAndVersion&0x0fff)<<48 | timeMid<<32 | timeLow
+	created = lnk.inLocation(time.Unix(0, (int64(timestamp)-uuidEpoch)*100).UTC())
+	mac = net.HardwareAddr(append([]byte(nil), id[10:]...))
+	return machine, mac, created, true
+}
+
+func readTrackerData(block []byte) (*TrackerData, error) {
+	if len(block) != trackerDataSize || endianness.Uint32(block) != trackerDataSize {
+		return nil, ErrInvalidSize
+	}
+
+	tracker := &TrackerData{
+		MachineID: cString(block[8:24]),
+	}
+	copy(tracker.DroidVolumeID[:], block[24:])
+	copy(tracker.DroidFileID[:], block[40:])
+	copy(tracker.BirthDroidVolumeID[:], block[56:])
+	copy(tracker.BirthDroidFileID[:], block[72:])
+	return tracker, nil
+}
+
+func (tracker *TrackerData) bytes() []byte {
+	block := make([]byte, trackerDataSize)
+	endianness.PutUint32(block, trackerDataSize)
+	// Version is zero
+	machineID := tracker.MachineID
+	if len(machineID) > 15 {
+		machineID = machineID[:15]
+	}
+	copy(block[8:], machineID)
+	copy(block[24:], tracker.DroidVolumeID[:])
+	copy(block[40:], tracker.DroidFileID[:])
+	copy(block[56:], tracker.BirthDroidVolumeID[:])
+	copy(block[72:], tracker.BirthDroidFileID[:])
+	return block
+}
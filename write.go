@@ -0,0 +1,484 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// WriteTo encodes the shortcut in the .lnk file format and writes it to w.
+// Only the fields of the LNK are written, so data the parser does not model
+// (such as known ExtraData blocks that are not decoded) is not preserved.
+func (lnk *LNK) WriteTo(w io.Writer) (int64, error) {
+	sections, err := lnk.encodeSections()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(bytes.Join(sections[:], nil))
+	return int64(n), err
+}
+
+// WriteToPreservingRaw writes the shortcut as WriteTo does, except that each
+// section whose fields are unchanged since it was parsed is written as the
+// bytes retained in its Raw field, so that the data it holds that is not
+// modeled survives. Only the sections whose fields changed are encoded again,
+// along with those that were not retained, either because
+// ParseOptions.RetainRaw was not set or because they were not parsed. A change
+// to the presence of a section also changes the LinkFlags, so the
+// ShellLinkHeader is then encoded again.
+//
+// Changes are detected by parsing the Raw fields again and comparing the
+// encoding of each of their sections with that of the fields, so a change that
+// does not affect the encoding, such as one to an ANSI variant of a LinkInfo
+// string, is not written. If the Raw fields cannot be parsed again, as when
+// they were retained from a shortcut that failed to parse, the error is
+// returned and nothing is written.
+func (lnk *LNK) WriteToPreservingRaw(w io.Writer) error {
+	sections, err := lnk.encodeSections()
+	if err != nil {
+		return err
+	}
+
+	raw := [...][]byte{lnk.RawHeader, lnk.RawIDList, lnk.RawLinkInfo, lnk.RawStringData, lnk.RawExtraData}
+	if len(lnk.RawHeader) != 0 {
+		original, err := ParseWithOptions(bytes.NewReader(bytes.Join(raw[:], nil)), ParseOptions{
+			DecodeANSI:        lnk.decodeANSI,
+			AllowForeignCLSID: true,
+		})
+		if err != nil {
+			return err
+		}
+		originalSections, err := original.encodeSections()
+		if err != nil {
+			return err
+		}
+		for i, section := range sectionOrder {
+			if len(raw[i]) != 0 && original.Parsed&section != 0 && bytes.Equal(sections[i], originalSections[i]) {
+				sections[i] = raw[i]
+			}
+		}
+	}
+
+	_, err = w.Write(bytes.Join(sections[:], nil))
+	return err
+}
+
+// sectionOrder lists the sections in the order they appear in a shortcut.
+var sectionOrder = [...]Section{SectionHeader, SectionIDList, SectionLinkInfo, SectionStringData, SectionExtraData}
+
+// encodeSections encodes each section of the shortcut, in the order of
+// sectionOrder.
+func (lnk *LNK) encodeSections() (sections [5][]byte, err error) {
+	err = lnk.LoadExtraData()
+	if err != nil {
+		return sections, err
+	}
+
+	var buf bytes.Buffer
+	// take returns what was written to buf since it was last called
+	take := func() []byte {
+		section := bytes.Clone(buf.Bytes())
+		buf.Reset()
+		return section
+	}
+
+	// ShellLinkHeader
+	write(&buf, uint32(76))
+	buf.Write(validCLSID[:])
+	write(&buf, lnk.linkFlags())
+	write(&buf, lnk.fileAttributes())
+	write(&buf, timeToWindowsNano(lnk.CreationTime))
+	write(&buf, timeToWindowsNano(lnk.AccessTime))
+	write(&buf, timeToWindowsNano(lnk.WriteTime))
+	write(&buf, lnk.FileSize)
+	write(&buf, lnk.IconIndex)
+	write(&buf, lnk.ShowCommand)
+	buf.WriteByte(lnk.HotKey.Key)
+	highByte := lnk.HotKey.Reserved &^ 0x07
+	if lnk.HotKey.Shift {
+		highByte |= 1 << 0
+	}
+	if lnk.HotKey.Ctrl {
+		highByte |= 1 << 1
+	}
+	if lnk.HotKey.Alt {
+		highByte |= 1 << 2
+	}
+	buf.WriteByte(highByte)
+	// Reserved1, Reserved2 and Reserved3
+	buf.Write(make([]byte, 10))
+	sections[0] = take()
+
+	// LinkTargetIDList
+	if len(lnk.IDListBytes) != 0 {
+		if len(lnk.IDListBytes) > 0xffff {
+			return sections, ErrInvalidSize
+		}
+		write(&buf, uint16(len(lnk.IDListBytes)))
+		buf.Write(lnk.IDListBytes)
+	}
+	sections[1] = take()
+
+	// LinkInfo
+	if lnk.HasLinkInfo {
+		buf.Write(lnk.linkInfo())
+	}
+	sections[2] = take()
+
+	// StringData
+	strs := []struct {
+		present bool
+		value   string
+	}{
+		{lnk.HasName, lnk.Name},
+		{lnk.HasRelativePath, lnk.RelativePath},
+		{lnk.HasWorkingDir, lnk.WorkingDir},
+		{lnk.HasArguments, lnk.Arguments},
+		{lnk.HasIconLocation, lnk.IconLocation},
+	}
+	for _, str := range strs {
+		if !str.present {
+			continue
+		}
+
+		if lnk.IsUnicode {
+			chars := utf16.Encode([]rune(str.value))
+			if len(chars) > 0xffff {
+				return sections, ErrInvalidSize
+			}
+			write(&buf, uint16(len(chars)))
+			write(&buf, chars)
+		} else {
+			if len(str.value) > 0xffff {
+				return sections, ErrInvalidSize
+			}
+			write(&buf, uint16(len(str.value)))
+			buf.WriteString(str.value)
+		}
+	}
+	sections[3] = take()
+
+	// ExtraData
+	blocks := lnk.extraDataBlocks()
+	unknown := lnk.UnknownBlocks
+	// blocks that were parsed keep their original order
+	for _, signature := range lnk.extraBlocks {
+		if block, ok := blocks[signature]; ok {
+			writeBlock(&buf, signature, block)
+			delete(blocks, signature)
+		} else if len(unknown) != 0 && unknown[0].Signature == signature {
+			writeBlock(&buf, signature, unknown[0].Data)
+			unknown = unknown[1:]
+		}
+	}
+	var signatures []uint32
+	for signature := range blocks {
+		signatures = append(signatures, signature)
+	}
+	sort.Slice(signatures, func(i, j int) bool { return signatures[i] < signatures[j] })
+	for _, signature := range signatures {
+		writeBlock(&buf, signature, blocks[signature])
+	}
+	for _, block := range unknown {
+		writeBlock(&buf, block.Signature, block.Data)
+	}
+	// TerminalBlock
+	write(&buf, uint32(0))
+	sections[4] = take()
+
+	return sections, nil
+}
+
+// MarshalBinary encodes the shortcut in the .lnk file format, as WriteTo does,
+// rather than in a package-specific encoding. It implements
+// encoding.BinaryMarshaler.
+func (lnk *LNK) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := lnk.WriteTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the shortcut with one parsed from data in the .lnk
+// file format. It implements encoding.BinaryUnmarshaler.
+func (lnk *LNK) UnmarshalBinary(data []byte) error {
+	parsed, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*lnk = *parsed
+	return nil
+}
+
+// linkFlags encodes the LinkFlags booleans, keeping the bits of the LinkFlags
+// field that have none.
+func (lnk *LNK) linkFlags() uint32 {
+	const modeled = 1<<27 - 1
+	return lnk.LinkFlags&^modeled | flagBits([]bool{
+		len(lnk.IDListBytes) != 0, lnk.HasLinkInfo, lnk.HasName,
+		lnk.HasRelativePath, lnk.HasWorkingDir, lnk.HasArguments,
+		lnk.HasIconLocation, lnk.IsUnicode, lnk.ForceNoLinkInfo,
+		lnk.HasExpString, lnk.RunInSeperateProcess, lnk.Unused1, lnk.HasDarwinID,
+		lnk.RunAsUser, lnk.HasExpIcon, lnk.NoPidlAlias, lnk.Unused2,
+		lnk.RunWithShimLayer, lnk.ForceNoLinkTrack, lnk.EnableTargetMetadata,
+		lnk.DisableLinkPathTracking, lnk.DisableKnownFolderTracking,
+		lnk.DisableKnownFolderAlias, lnk.AllowLinkToLink, lnk.UnaliasOnSave,
+		lnk.PreferEnvironmentPath, lnk.KeepLocalIDListForUNCTarget,
+	})
+}
+
+// fileAttributes encodes the FileAttributes booleans, keeping the bits of the
+// FileAttributes field that have none.
+func (lnk *LNK) fileAttributes() uint32 {
+	const modeled = 1<<15 - 1
+	return lnk.FileAttributes&^modeled | flagBits([]bool{
+		lnk.ReadOnly, lnk.Hidden, lnk.System, false, lnk.Directory,
+		lnk.Archive, false, lnk.Normal, lnk.Temporary, lnk.SparseFile,
+		lnk.ReparsePoint, lnk.Compressed, lnk.Offline, lnk.NotContentIndexed,
+		lnk.Encrypted,
+	})
+}
+
+// linkInfo encodes the LinkInfo structure. The ANSI strings are written from
+// the plain fields, with any character that is not ASCII replaced by '?'. A
+// string is also written in Unicode if its Unicode variant is set, or
+// otherwise if the plain field is not ASCII, in which case the header is
+// enlarged to 0x24 bytes to hold the Unicode offsets. A volume label written
+// in Unicode is only written in Unicode, as the VolumeID has no room for both.
+func (lnk *LNK) linkInfo() []byte {
+	localBasePathUnicode := unicodeVariant(lnk.LocalBasePath, lnk.LocalBasePathUnicode)
+	commonPathSuffixUnicode := unicodeVariant(lnk.CommonPathSuffix, lnk.CommonPathSuffixUnicode)
+	headerSize := uint32(0x1c)
+	if lnk.VolumeIDAndLocalBasePath && localBasePathUnicode != "" || commonPathSuffixUnicode != "" {
+		headerSize = 0x24
+	}
+	var body bytes.Buffer
+	var flags, volumeIDOffset, localBasePathOffset, localBasePathOffsetUnicode uint32
+
+	if lnk.VolumeIDAndLocalBasePath {
+		flags |= 1 << 0
+
+		volumeIDOffset = headerSize + uint32(body.Len())
+		if label := unicodeVariant(lnk.VolumeLabel, lnk.VolumeLabelUnicode); label != "" {
+			chars := utf16.Encode([]rune(label + "\x00"))
+			write(&body, uint32(0x14+2*len(chars)))
+			write(&body, lnk.DriveType)
+			write(&body, lnk.DriveSerialNumber)
+			// VolumeLabelOffset, then VolumeLabelOffsetUnicode
+			write(&body, uint32(0x14))
+			write(&body, uint32(0x14))
+			write(&body, chars)
+		} else {
+			write(&body, uint32(0x10+len(lnk.VolumeLabel)+1))
+			write(&body, lnk.DriveType)
+			write(&body, lnk.DriveSerialNumber)
+			// VolumeLabelOffset
+			write(&body, uint32(0x10))
+			body.WriteString(lnk.VolumeLabel + "\x00")
+		}
+
+		localBasePathOffset = headerSize + uint32(body.Len())
+		body.WriteString(encodeANSI(lnk.LocalBasePath) + "\x00")
+	}
+
+	var commonNetworkRelativeLinkOffset uint32
+	if lnk.CommonNetworkRelativeLinkAndPathSuffix {
+		flags |= 1 << 1
+		commonNetworkRelativeLinkOffset = headerSize + uint32(body.Len())
+		body.Write(lnk.commonNetworkRelativeLink())
+	}
+
+	commonPathSuffixOffset := headerSize + uint32(body.Len())
+	body.WriteString(encodeANSI(lnk.CommonPathSuffix) + "\x00")
+
+	if lnk.VolumeIDAndLocalBasePath && localBasePathUnicode != "" {
+		localBasePathOffsetUnicode = headerSize + uint32(body.Len())
+		write(&body, utf16.Encode([]rune(localBasePathUnicode+"\x00")))
+	}
+	var commonPathSuffixOffsetUnicode uint32
+	if commonPathSuffixUnicode != "" {
+		commonPathSuffixOffsetUnicode = headerSize + uint32(body.Len())
+		write(&body, utf16.Encode([]rune(commonPathSuffixUnicode+"\x00")))
+	}
+
+	var buf bytes.Buffer
+	write(&buf, headerSize+uint32(body.Len()))
+	write(&buf, headerSize)
+	write(&buf, flags)
+	write(&buf, volumeIDOffset)
+	write(&buf, localBasePathOffset)
+	write(&buf, commonNetworkRelativeLinkOffset)
+	write(&buf, commonPathSuffixOffset)
+	if headerSize == 0x24 {
+		write(&buf, localBasePathOffsetUnicode)
+		write(&buf, commonPathSuffixOffsetUnicode)
+	}
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// commonNetworkRelativeLink encodes the CommonNetworkRelativeLink of the
+// LinkInfo. Its strings are written as linkInfo writes those of the LinkInfo,
+// with the Unicode offsets only present if a string is written in Unicode.
+func (lnk *LNK) commonNetworkRelativeLink() []byte {
+	netNameUnicode := unicodeVariant(lnk.NetName, lnk.NetNameUnicode)
+	var deviceNameUnicode string
+	if lnk.ValidDevice {
+		deviceNameUnicode = unicodeVariant(lnk.DeviceName, lnk.DeviceNameUnicode)
+	}
+	headerSize := uint32(0x14)
+	if netNameUnicode != "" || deviceNameUnicode != "" {
+		headerSize = 0x1c
+	}
+
+	var flags, deviceNameOffset, networkProviderType uint32
+	names := encodeANSI(lnk.NetName) + "\x00"
+	if lnk.ValidDevice {
+		flags |= 1 << 0
+		deviceNameOffset = headerSize + uint32(len(names))
+		names += encodeANSI(lnk.DeviceName) + "\x00"
+	}
+	if lnk.ValidNetType {
+		flags |= 1 << 1
+		networkProviderType = lnk.NetworkProviderType
+	}
+	var namesUnicode []uint16
+	var netNameOffsetUnicode, deviceNameOffsetUnicode uint32
+	if netNameUnicode != "" {
+		netNameOffsetUnicode = headerSize + uint32(len(names))
+		namesUnicode = utf16.Encode([]rune(netNameUnicode + "\x00"))
+	}
+	if deviceNameUnicode != "" {
+		deviceNameOffsetUnicode = headerSize + uint32(len(names)+2*len(namesUnicode))
+		namesUnicode = append(namesUnicode, utf16.Encode([]rune(deviceNameUnicode+"\x00"))...)
+	}
+
+	var buf bytes.Buffer
+	write(&buf, headerSize+uint32(len(names)+2*len(namesUnicode)))
+	write(&buf, flags)
+	// NetNameOffset
+	write(&buf, headerSize)
+	write(&buf, deviceNameOffset)
+	write(&buf, networkProviderType)
+	if headerSize == 0x1c {
+		write(&buf, netNameOffsetUnicode)
+		write(&buf, deviceNameOffsetUnicode)
+	}
+	buf.WriteString(names)
+	write(&buf, namesUnicode)
+	return buf.Bytes()
+}
+
+// unicodeVariant returns the string to write in Unicode for a LinkInfo string
+// whose plain field is str and whose Unicode variant is unicode, which is
+// empty if it need only be written in ANSI.
+func unicodeVariant(str, unicode string) string {
+	if unicode != "" {
+		return unicode
+	}
+	if !isASCII(str) {
+		return str
+	}
+	return ""
+}
+
+// encodeANSI replaces each character of str that is not ASCII with '?', as
+// Windows does for characters missing from the code page.
+func encodeANSI(str string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf {
+			return '?'
+		}
+		return r
+	}, str)
+}
+
+// extraDataBlocks encodes the data of each ExtraData block that is modeled,
+// keyed by signature.
+func (lnk *LNK) extraDataBlocks() map[uint32][]byte {
+	blocks := make(map[uint32][]byte)
+
+	if lnk.EnvironmentVariable != nil {
+		blocks[EnvironmentVariableDataBlockSignature] = lnk.EnvironmentVariable.bytes()
+	}
+
+	if lnk.IconEnvironment != nil {
+		blocks[IconEnvironmentDataBlockSignature] = lnk.IconEnvironment.bytes()
+	}
+
+	if lnk.Console != nil {
+		blocks[ConsoleDataBlockSignature] = lnk.Console.bytes()
+	}
+
+	if lnk.Tracker != nil {
+		blocks[TrackerDataBlockSignature] = lnk.Tracker.bytes()
+	}
+
+	if lnk.Darwin != nil {
+		blocks[DarwinDataBlockSignature] = lnk.Darwin.bytes()
+	}
+
+	if lnk.VistaAndAboveIDList != nil {
+		blocks[VistaAndAboveIDListDataBlockSignature] = lnk.VistaAndAboveIDList
+	}
+
+	if lnk.SpecialFolder != nil {
+		var block bytes.Buffer
+		write(&block, lnk.SpecialFolder.ID)
+		write(&block, lnk.SpecialFolder.Offset)
+		blocks[SpecialFolderDataBlockSignature] = block.Bytes()
+	}
+
+	if lnk.KnownFolder != nil {
+		var block bytes.Buffer
+		block.Write(lnk.KnownFolder.ID[:])
+		write(&block, lnk.KnownFolder.Offset)
+		blocks[KnownFolderDataBlockSignature] = block.Bytes()
+	}
+
+	if lnk.PropertyStore != nil {
+		blocks[PropertyStoreDataBlockSignature] = writePropertyStore(lnk.PropertyStore)
+	}
+
+	return blocks
+}
+
+func writeBlock(buf *bytes.Buffer, signature uint32, data []byte) {
+	write(buf, uint32(8+len(data)))
+	write(buf, signature)
+	buf.Write(data)
+}
+
+// write writes the little-endian encoding of a fixed-size value. Writes to a
+// bytes.Buffer cannot fail.
+func write(buf *bytes.Buffer, data interface{}) {
+	_ = binary.Write(buf, endianness, data)
+}
+
+// flagBits packs flags into an integer, the first flag being the lowest bit.
+func flagBits(flags []bool) uint32 {
+	var bits uint32
+	for i, flag := range flags {
+		if flag {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits
+}
+
+// timeToWindowsNano is the inverse of windowsNanoToTime. It returns the number
+// of 100-nanosecond intervals since 1601-01-01 UTC, or 0 for the zero time.
+func timeToWindowsNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano()/100 + 116444736000000000)
+}
@@ -0,0 +1,189 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	for _, name := range []string{"local.lnk", "unc.lnk", "roundtrip.lnk", "emoji_li.lnk"} {
+		lnk := load(t, name)
+		data, err := lnk.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		got := new(LNK)
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, lnk) {
+			t.Errorf("%s: round trip changed the shortcut:\n%+v\nwant:\n%+v", name, got, lnk)
+		}
+	}
+}
+
+func TestWriteTimestamps(t *testing.T) {
+	for _, name := range []string{"local.lnk", "knownfolder.lnk", "console.lnk"} {
+		data := readTestdata(t, name)
+		written, err := load(t, name).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// CreationTime, AccessTime and WriteTime
+		if !bytes.Equal(written[28:52], data[28:52]) {
+			t.Errorf("%s: timestamps = %x, want %x", name, written[28:52], data[28:52])
+		}
+	}
+}
+
+func TestTimeToWindowsNano(t *testing.T) {
+	tests := []struct {
+		time time.Time
+		nano uint64
+	}{
+		{time.Time{}, 0},
+		{time.Date(2019, 4, 17, 18, 40, 0, 0, time.UTC), 132000000000000000},
+		{time.Date(1900, 1, 1, 0, 0, 0, 100, time.UTC), 94354848000000001},
+	}
+	for _, test := range tests {
+		if got := timeToWindowsNano(test.time); got != test.nano {
+			t.Errorf("timeToWindowsNano(%v) = %d, want %d", test.time, got, test.nano)
+		}
+		if test.nano != 0 && !windowsNanoToTime(test.nano).Equal(test.time) {
+			t.Errorf("windowsNanoToTime(%d) = %v, want %v", test.nano, windowsNanoToTime(test.nano), test.time)
+		}
+	}
+}
+
+func TestWriteRawFlags(t *testing.T) {
+	lnk := load(t, "local.lnk")
+	if lnk.LinkFlags != 0xbb {
+		t.Errorf("LinkFlags = %#x, want %#x", lnk.LinkFlags, 0xbb)
+	}
+
+	// bits without a field, such as reserved ones, are kept
+	lnk.LinkFlags |= 1 << 30
+	lnk.FileAttributes |= 0x10000
+	lnk.ReadOnly = true
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LinkFlags != lnk.LinkFlags || got.FileAttributes != lnk.FileAttributes|0x1 || !got.ReadOnly {
+		t.Errorf("LinkFlags = %#x, FileAttributes = %#x, want %#x, %#x", got.LinkFlags, got.FileAttributes, lnk.LinkFlags, lnk.FileAttributes|0x1)
+	}
+}
+
+func TestWriteUnicodeLinkInfo(t *testing.T) {
+	lnk := &LNK{
+		CLSID:                                  validCLSID,
+		IsUnicode:                              true,
+		HasLinkInfo:                            true,
+		VolumeIDAndLocalBasePath:               true,
+		CommonNetworkRelativeLinkAndPathSuffix: true,
+		VolumeLabel:                            "Données",
+		LocalBasePath:                          `C:\café`,
+		NetName:                                `\\serveur\données`,
+		ValidDevice:                            true,
+		DeviceName:                             "Z:",
+		CommonPathSuffix:                       "é.txt",
+	}
+	data, err := lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ANSI strings replace what is not ASCII, and the Unicode ones keep it
+	tests := []struct {
+		name, ansi, unicode   string
+		wantANSI, wantUnicode string
+	}{
+		{"VolumeLabel", got.VolumeLabelANSI, got.VolumeLabelUnicode, "", "Données"},
+		{"LocalBasePath", got.LocalBasePathANSI, got.LocalBasePathUnicode, `C:\caf?`, `C:\café`},
+		{"NetName", got.NetNameANSI, got.NetNameUnicode, `\\serveur\donn?es`, `\\serveur\données`},
+		{"DeviceName", got.DeviceNameANSI, got.DeviceNameUnicode, "Z:", ""},
+		{"CommonPathSuffix", got.CommonPathSuffixANSI, got.CommonPathSuffixUnicode, "?.txt", "é.txt"},
+	}
+	for _, test := range tests {
+		if test.ansi != test.wantANSI || test.unicode != test.wantUnicode {
+			t.Errorf("%s = %q, %q, want %q, %q", test.name, test.ansi, test.unicode, test.wantANSI, test.wantUnicode)
+		}
+	}
+
+	// ASCII strings are written in ANSI alone, in a header of the minimum size
+	lnk = load(t, "local.lnk")
+	data, err = lnk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LocalBasePathUnicode != "" || got.LinkInfoSize != lnk.LinkInfoSize {
+		t.Errorf("LocalBasePathUnicode = %q, LinkInfoSize = %d, want %q, %d", got.LocalBasePathUnicode, got.LinkInfoSize, "", lnk.LinkInfoSize)
+	}
+}
+
+func TestWriteToPreservingRaw(t *testing.T) {
+	for _, name := range []string{"local.lnk", "unicode_li.lnk", "tracker.lnk", "vendor.lnk", "unc_dev.lnk", "console.lnk"} {
+		data := readTestdata(t, name)
+		lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{RetainRaw: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := lnk.WriteToPreservingRaw(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%s: unchanged shortcut written differently", name)
+		}
+	}
+
+	// only the StringData of local.lnk, from 347 to 397, is encoded again
+	data := readTestdata(t, "local.lnk")
+	lnk, err := ParseWithOptions(bytes.NewReader(data), ParseOptions{RetainRaw: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lnk.Arguments = "--changed"
+	var buf bytes.Buffer
+	if err := lnk.WriteToPreservingRaw(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.Bytes()
+	if !bytes.Equal(got[:347], data[:347]) {
+		t.Error("sections before the StringData changed")
+	}
+	if !bytes.HasSuffix(got, data[397:]) {
+		t.Error("ExtraData changed")
+	}
+	written, err := Parse(bytes.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Arguments != "--changed" || written.WorkingDir != `C:\test` || written.RelativePath != `.\a.txt` {
+		t.Errorf("StringData = %q, %q, %q", written.RelativePath, written.WorkingDir, written.Arguments)
+	}
+
+	// Raw fields that cannot be parsed again are an error
+	lnk.RawLinkInfo = bytes.Clone(lnk.RawLinkInfo)
+	endianness.PutUint32(lnk.RawLinkInfo[4:], 0)
+	buf.Reset()
+	if err := lnk.WriteToPreservingRaw(&buf); !errors.Is(err, ErrInvalidSize) || buf.Len() != 0 {
+		t.Errorf("WriteToPreservingRaw() = %v with %d bytes written, want %v", err, buf.Len(), ErrInvalidSize)
+	}
+}
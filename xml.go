@@ -0,0 +1,151 @@
+package lnk
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// xmlShortcut is the layout of the XML produced by MarshalXML. Elements are
+// named after the fields of the LNK, as in the JSON produced by MarshalJSON.
+type xmlShortcut struct {
+	Schema       string `xml:"_schema,attr"`
+	Parser       string `xml:"parser,attr"`
+	CreationTime string `xml:"CreationTime,attr,omitempty"`
+	AccessTime   string `xml:"AccessTime,attr,omitempty"`
+	WriteTime    string `xml:"WriteTime,attr,omitempty"`
+
+	Target         string    `xml:"Target,omitempty"`
+	LinkFlags      xmlFlags  `xml:"LinkFlags"`
+	FileAttributes xmlFlags  `xml:"FileAttributes"`
+	FileSize       uint32    `xml:"FileSize"`
+	IconIndex      int32     `xml:"IconIndex"`
+	ShowCommand    uint32    `xml:"ShowCommand"`
+	HotKey         string    `xml:"HotKey,omitempty"`
+	Name           *string   `xml:"Name"`
+	RelativePath   *string   `xml:"RelativePath"`
+	WorkingDir     *string   `xml:"WorkingDir"`
+	Arguments      *string   `xml:"Arguments"`
+	IconLocation   *string   `xml:"IconLocation"`
+	LinkInfo       *xmlInfo  `xml:"LinkInfo"`
+	ExtraData      *xmlExtra `xml:"ExtraData"`
+}
+
+// xmlFlags is a set of flags, with the value as read from the file.
+type xmlFlags struct {
+	Value string   `xml:"value,attr"`
+	Flags []string `xml:"Flag"`
+}
+
+type xmlInfo struct {
+	DriveType         uint32 `xml:"DriveType,omitempty"`
+	DriveSerialNumber string `xml:"DriveSerialNumber,omitempty"`
+	VolumeLabel       string `xml:"VolumeLabel,omitempty"`
+	LocalBasePath     string `xml:"LocalBasePath,omitempty"`
+	NetName           string `xml:"NetName,omitempty"`
+	DeviceName        string `xml:"DeviceName,omitempty"`
+	CommonPathSuffix  string `xml:"CommonPathSuffix,omitempty"`
+}
+
+type xmlExtra struct {
+	Blocks              []string `xml:"Block"`
+	EnvironmentVariable string   `xml:"EnvironmentVariable,omitempty"`
+	IconEnvironment     string   `xml:"IconEnvironment,omitempty"`
+	MachineID           string   `xml:"MachineID,omitempty"`
+	KnownFolder         string   `xml:"KnownFolder,omitempty"`
+	SpecialFolder       string   `xml:"SpecialFolder,omitempty"`
+	AppUserModelID      string   `xml:"AppUserModelID,omitempty"`
+}
+
+// MarshalXML encodes the shortcut as a <shortcut> element with the same
+// "_schema" and "parser" as MarshalJSON and its timestamps in RFC 3339 as
+// attributes. Its children are the target returned by ResolveTarget, the flags
+// and other header fields, the StringData that is present, and the main
+// fields of the LinkInfo and the ExtraData, named after the fields of the LNK.
+// The element is named "shortcut" unless start is named after a struct field.
+// It implements xml.Marshaler.
+func (lnk *LNK) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	err := lnk.LoadExtraData()
+	if err != nil {
+		return err
+	}
+
+	if start.Name.Local == "" || start.Name.Local == "LNK" {
+		start.Name = xml.Name{Local: "shortcut"}
+	}
+
+	timestamp := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	present := func(present bool, str string) *string {
+		if !present {
+			return nil
+		}
+		return &str
+	}
+
+	target, _ := lnk.ResolveTarget()
+	shortcut := xmlShortcut{
+		Schema:       JSONSchema,
+		Parser:       jsonParser,
+		CreationTime: timestamp(lnk.CreationTime),
+		AccessTime:   timestamp(lnk.AccessTime),
+		WriteTime:    timestamp(lnk.WriteTime),
+
+		Target:         target,
+		LinkFlags:      xmlFlags{fmt.Sprintf("0x%08x", lnk.LinkFlags), lnk.linkFlagNames()},
+		FileAttributes: xmlFlags{fmt.Sprintf("0x%08x", lnk.FileAttributes), lnk.fileAttributeNames()},
+		FileSize:       lnk.FileSize,
+		IconIndex:      lnk.IconIndex,
+		ShowCommand:    lnk.ShowCommand,
+		Name:           present(lnk.HasName, lnk.Name),
+		RelativePath:   present(lnk.HasRelativePath, lnk.RelativePath),
+		WorkingDir:     present(lnk.HasWorkingDir, lnk.WorkingDir),
+		Arguments:      present(lnk.HasArguments, lnk.Arguments),
+		IconLocation:   present(lnk.HasIconLocation, lnk.IconLocation),
+	}
+	if lnk.HotKey.Key != 0 {
+		shortcut.HotKey = lnk.HotKey.String()
+	}
+
+	if lnk.HasLinkInfo {
+		info := &xmlInfo{
+			LocalBasePath:    lnk.LocalBasePath,
+			NetName:          lnk.NetName,
+			DeviceName:       lnk.DeviceName,
+			CommonPathSuffix: lnk.CommonPathSuffix,
+		}
+		if lnk.VolumeIDAndLocalBasePath {
+			info.DriveType = lnk.DriveType
+			info.DriveSerialNumber = lnk.DriveSerial()
+			info.VolumeLabel = lnk.VolumeLabel
+		}
+		shortcut.LinkInfo = info
+	}
+
+	if len(lnk.extraBlocks) != 0 {
+		extra := &xmlExtra{Blocks: lnk.ExtraBlockNames()}
+		if lnk.EnvironmentVariable != nil {
+			extra.EnvironmentVariable = lnk.EnvironmentVariable.Target()
+		}
+		if lnk.IconEnvironment != nil {
+			extra.IconEnvironment = lnk.IconEnvironment.Target()
+		}
+		if lnk.Tracker != nil {
+			extra.MachineID = lnk.Tracker.MachineID
+		}
+		if lnk.KnownFolder != nil {
+			extra.KnownFolder = lnk.KnownFolder.ID.String()
+		}
+		if lnk.SpecialFolder != nil {
+			extra.SpecialFolder = fmt.Sprint(lnk.SpecialFolder.ID)
+		}
+		extra.AppUserModelID, _ = lnk.AppUserModelID()
+		shortcut.ExtraData = extra
+	}
+
+	return e.EncodeElement(shortcut, start)
+}
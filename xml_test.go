@@ -0,0 +1,39 @@
+package lnk
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestGoldenXML(t *testing.T) {
+	for _, name := range []string{"local.lnk", "unc.lnk", "uwp.lnk"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := xml.MarshalIndent(load(t, name), "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "testdata/"+strings.TrimSuffix(name, ".lnk")+".xml.golden", append(got, '\n'))
+		})
+	}
+}
+
+func TestXMLFlagNames(t *testing.T) {
+	// the flags are named as the fields and JSON keys are
+	lnk := load(t, "local.lnk")
+	lnk.RunInSeperateProcess = true
+	data, err := xml.Marshal(lnk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "RunInSeperateProcess") {
+		t.Errorf("XML does not name RunInSeperateProcess: %s", data)
+	}
+	data, err = lnk.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"RunInSeperateProcess":true`) {
+		t.Errorf("JSON does not name RunInSeperateProcess: %s", data)
+	}
+}
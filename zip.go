@@ -0,0 +1,48 @@
+package lnk
+
+import (
+	"archive/zip"
+	"io"
+	"path"
+	"strings"
+)
+
+// ParseZip parses every .lnk entry of the zip archive in r, which is size
+// bytes long, without extracting it. It returns the shortcuts that were
+// parsed, in the order of their entries, and the errors of those that were
+// not, keyed by entry name. If the archive itself cannot be read, its error
+// is keyed by the empty name.
+func ParseZip(r io.ReaderAt, size int64) ([]*LNK, map[string]error) {
+	errs := make(map[string]error)
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		errs[""] = err
+		return nil, errs
+	}
+
+	var lnks []*LNK
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), ".lnk") {
+			continue
+		}
+
+		lnk, err := parseZipFile(file)
+		if err != nil {
+			errs[file.Name] = err
+			continue
+		}
+		lnks = append(lnks, lnk)
+	}
+	return lnks, errs
+}
+
+// parseZipFile parses a single entry of the archive.
+func parseZipFile(file *zip.File) (*LNK, error) {
+	entry, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer entry.Close()
+
+	return Parse(entry)
+}
@@ -0,0 +1,49 @@
+package lnk
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParseZip(t *testing.T) {
+	good := readTestdata(t, "local.lnk")
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{"shortcuts/good.lnk", good},
+		{"bad.LNK", good[:40]},
+		{"readme.txt", []byte("not a shortcut")},
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		f, err := w.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lnks, errs := ParseZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if len(lnks) != 1 || lnks[0].LocalBasePath != `C:\test\a.txt` {
+		t.Errorf("ParseZip() = %d shortcuts, want good.lnk", len(lnks))
+	}
+	if len(errs) != 1 || !errors.Is(errs["bad.LNK"], io.ErrUnexpectedEOF) {
+		t.Errorf("ParseZip() errors = %v, want one for bad.LNK", errs)
+	}
+
+	lnks, errs = ParseZip(bytes.NewReader([]byte("not a zip")), 9)
+	if lnks != nil || len(errs) != 1 || errs[""] == nil {
+		t.Errorf("ParseZip() = %v, %v, want an error for the archive", lnks, errs)
+	}
+}